@@ -0,0 +1,127 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/tsukinoko-kun/jmod/registry"
+)
+
+type Severity uint8
+
+const (
+	SeverityInfo Severity = iota
+	SeverityLow
+	SeverityModerate
+	SeverityHigh
+	SeverityCritical
+)
+
+var severityNames = []string{"info", "low", "moderate", "high", "critical"}
+
+func (s Severity) String() string {
+	if int(s) < len(severityNames) {
+		return severityNames[s]
+	}
+	return "unknown"
+}
+
+func ParseSeverity(s string) (Severity, error) {
+	for i, name := range severityNames {
+		if strings.EqualFold(s, name) {
+			return Severity(i), nil
+		}
+	}
+	return SeverityInfo, fmt.Errorf("invalid severity %q (expected one of %s)", s, strings.Join(severityNames, ", "))
+}
+
+// Finding is an advisory affecting an installed package version.
+type Finding struct {
+	Advisory registry.Advisory          `json:"advisory"`
+	Severity Severity                   `json:"-"`
+	Package  string                     `json:"package"`
+	Version  string                     `json:"version"`
+	Chains   []registry.DependencyChain `json:"chains"`
+}
+
+// Run audits the packages installed below root.
+// Advisories matching an entry of ignore by id, URL or GHSA id are skipped.
+func Run(ctx context.Context, root string, ignore []string) ([]Finding, error) {
+	packages, err := Installed(root)
+	if err != nil {
+		return nil, err
+	}
+	if len(packages) == 0 {
+		return nil, nil
+	}
+
+	versions := map[string][]string{}
+	for _, pkg := range packages {
+		versions[pkg.Name] = append(versions[pkg.Name], pkg.Version)
+	}
+	advisories, err := registry.Npm_BulkAdvisories(ctx, versions)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	for _, pkg := range packages {
+		for _, advisory := range advisories[pkg.Name] {
+			if isIgnored(advisory, ignore) || !affects(advisory, pkg.Version) {
+				continue
+			}
+			severity, err := ParseSeverity(advisory.Severity)
+			if err != nil {
+				severity = SeverityInfo
+			}
+			findings = append(findings, Finding{
+				Advisory: advisory,
+				Severity: severity,
+				Package:  pkg.Name,
+				Version:  pkg.Version,
+				Chains:   pkg.Chains,
+			})
+		}
+	}
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].Severity != findings[j].Severity {
+			return findings[i].Severity > findings[j].Severity
+		}
+		if findings[i].Package != findings[j].Package {
+			return findings[i].Package < findings[j].Package
+		}
+		if findings[i].Version != findings[j].Version {
+			return findings[i].Version < findings[j].Version
+		}
+		return findings[i].Advisory.Id < findings[j].Advisory.Id
+	})
+	return findings, nil
+}
+
+// affects checks the installed version against the vulnerable range.
+// Unparsable ranges are reported to stay on the safe side.
+func affects(advisory registry.Advisory, version string) bool {
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return true
+	}
+	c, err := semver.NewConstraint(advisory.VulnerableVersions)
+	if err != nil {
+		return true
+	}
+	return c.Check(v)
+}
+
+func isIgnored(advisory registry.Advisory, ignore []string) bool {
+	id := strconv.FormatInt(advisory.Id, 10)
+	for _, entry := range ignore {
+		if entry == id || entry == advisory.Url || strings.HasSuffix(advisory.Url, "/"+entry) {
+			return true
+		}
+	}
+	return false
+}
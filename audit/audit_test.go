@@ -0,0 +1,42 @@
+package audit
+
+import (
+	"testing"
+
+	"github.com/tsukinoko-kun/jmod/registry"
+)
+
+func TestAffects(t *testing.T) {
+	advisory := registry.Advisory{VulnerableVersions: "<4.17.21"}
+	if !affects(advisory, "4.17.20") {
+		t.Error("expected 4.17.20 to be affected")
+	}
+	if affects(advisory, "4.17.21") {
+		t.Error("expected 4.17.21 not to be affected")
+	}
+	if !affects(registry.Advisory{VulnerableVersions: "not a range"}, "1.0.0") {
+		t.Error("expected unparsable ranges to be reported")
+	}
+}
+
+func TestIsIgnored(t *testing.T) {
+	advisory := registry.Advisory{Id: 1106913, Url: "https://github.com/advisories/GHSA-jf85-cpcp-j695"}
+	for _, entry := range []string{"1106913", "GHSA-jf85-cpcp-j695", advisory.Url} {
+		if !isIgnored(advisory, []string{entry}) {
+			t.Errorf("expected %q to ignore the advisory", entry)
+		}
+	}
+	if isIgnored(advisory, []string{"GHSA-xxxx-xxxx-xxxx", "1"}) {
+		t.Error("unexpected match")
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	s, err := ParseSeverity("High")
+	if err != nil || s != SeverityHigh {
+		t.Errorf("got %v, %v", s, err)
+	}
+	if _, err := ParseSeverity("urgent"); err == nil {
+		t.Error("expected an error")
+	}
+}
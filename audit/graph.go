@@ -0,0 +1,96 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/tsukinoko-kun/jmod/config"
+	"github.com/tsukinoko-kun/jmod/registry"
+	"github.com/tsukinoko-kun/jmod/utils"
+)
+
+// maxChains limits how many dependency chains are recorded per package version
+const maxChains = 10
+
+// Package is an installed package version and the dependency chains it is reachable by.
+type Package struct {
+	Name    string
+	Version string
+	Chains  []registry.DependencyChain
+}
+
+type manifest struct {
+	Name                 string            `json:"name"`
+	Version              string            `json:"version"`
+	Dependencies         map[string]string `json:"dependencies"`
+	OptionalDependencies map[string]string `json:"optionalDependencies"`
+}
+
+// Installed walks the node_modules links of all modules below root
+// and returns the installed packages keyed by name@version.
+func Installed(root string) (map[string]*Package, error) {
+	packages := map[string]*Package{}
+	for _, modDoc := range config.FindSubMods(root) {
+		mod := modDoc.TypedData
+		modDir := filepath.Dir(mod.GetFileLocation())
+		chain := registry.DependencyChain{utils.Must(filepath.Rel(root, mod.GetFileLocation()))}
+		var deps []string
+		for _, m := range []map[string]string{mod.NpmDependencies, mod.NpmDevDependencies, mod.NpmOptionalDependencies} {
+			for name := range m {
+				deps = append(deps, name)
+			}
+		}
+		if err := visit(packages, modDir, deps, chain); err != nil {
+			return nil, err
+		}
+	}
+	return packages, nil
+}
+
+func visit(packages map[string]*Package, parentDir string, deps []string, chain registry.DependencyChain) error {
+	slices.Sort(deps)
+	for _, dep := range slices.Compact(deps) {
+		location, err := filepath.EvalSymlinks(filepath.Join(parentDir, "node_modules", dep))
+		if err != nil {
+			// not installed, e.g. an optional dependency for another platform
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(location, "package.json"))
+		if err != nil {
+			return fmt.Errorf("read manifest of %s: %w", chain.With(dep).String(), err)
+		}
+		var m manifest
+		if err := json.Unmarshal(data, &m); err != nil {
+			return fmt.Errorf("decode manifest of %s: %w", chain.With(dep).String(), err)
+		}
+		key := fmt.Sprintf("%s@%s", m.Name, m.Version)
+		depChain := chain.With(key)
+
+		if pkg, ok := packages[key]; ok {
+			if len(pkg.Chains) < maxChains {
+				pkg.Chains = append(pkg.Chains, depChain)
+			}
+			continue
+		}
+		packages[key] = &Package{
+			Name:    m.Name,
+			Version: m.Version,
+			Chains:  []registry.DependencyChain{depChain},
+		}
+
+		var childDeps []string
+		for name := range m.Dependencies {
+			childDeps = append(childDeps, name)
+		}
+		for name := range m.OptionalDependencies {
+			childDeps = append(childDeps, name)
+		}
+		if err := visit(packages, location, childDeps, depChain); err != nil {
+			return err
+		}
+	}
+	return nil
+}
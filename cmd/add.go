@@ -5,7 +5,6 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/tsukinoko-kun/jmod/config"
-	"github.com/tsukinoko-kun/jmod/install"
 	"github.com/tsukinoko-kun/jmod/logger"
 	"github.com/tsukinoko-kun/jmod/meta"
 	"github.com/tsukinoko-kun/jmod/registry"
@@ -27,6 +26,8 @@ var addCmd = &cobra.Command{
 			return cmd.Help()
 		}
 
+		applyPlatformFlags(cmd)
+		applyEngineFlags(cmd)
 		ctx := cmd.Context()
 
 		mod := cmd.Flag("mod").Value.String()
@@ -47,9 +48,11 @@ var addCmd = &cobra.Command{
 			return err
 		}
 
-		install.Run(ctx, meta.Pwd(), utils.Must(cmd.Flags().GetBool("ignore-scripts")), true, true, nil)
-
-		return nil
+		opts, err := installOptions(cmd)
+		if err != nil {
+			return err
+		}
+		return runInstall(ctx, opts)
 	},
 }
 
@@ -57,5 +60,7 @@ func init() {
 	rootCmd.AddCommand(addCmd)
 	addCmd.Flags().String("mod", ".", "module to add the dependency to")
 	addCmd.Flags().BoolP("dev", "D", false, "add as a dev dependency")
-	addCmd.Flags().Bool("ignore-scripts", false, "Ignore scripts in package.json")
+	addInstallFlags(addCmd)
+	addPlatformFlags(addCmd)
+	addEngineFlags(addCmd)
 }
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tsukinoko-kun/jmod/install"
+	"github.com/tsukinoko-kun/jmod/logger"
+	"github.com/tsukinoko-kun/jmod/meta"
+	"github.com/tsukinoko-kun/jmod/statusui"
+)
+
+var approveBuildsCmd = &cobra.Command{
+	Use:   "approve-builds [package...]",
+	Short: "Allow lifecycle scripts of dependencies",
+	Long:  "Adds the given packages to trustedDependencies. Without arguments, all dependencies whose scripts were skipped by the last install are added.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		names := args
+		if len(names) == 0 {
+			blocked, err := install.ReadBlockedScripts(meta.Pwd())
+			if err != nil {
+				return fmt.Errorf("read skipped lifecycle scripts: %w", err)
+			}
+			if len(blocked) == 0 {
+				logger.Printf("no dependencies with skipped lifecycle scripts")
+				return nil
+			}
+			names = blocked
+		}
+
+		added, err := install.ApproveBuilds(meta.Pwd(), names)
+		if err != nil {
+			return err
+		}
+		if len(added) == 0 {
+			logger.Printf("all packages are already trusted")
+			return nil
+		}
+		logger.Printf("added %s to trustedDependencies", strings.Join(added, ", "))
+
+		if err := statusui.Start(); err != nil {
+			return err
+		}
+		defer statusui.Stop()
+
+		applyPlatformFlags(cmd)
+		applyEngineFlags(cmd)
+		opts, err := installOptions(cmd)
+		if err != nil {
+			return err
+		}
+		return runInstall(cmd.Context(), opts)
+	},
+}
+
+// reportBlockedScripts lists the dependencies whose lifecycle scripts were skipped
+// and records them for approve-builds.
+func reportBlockedScripts() {
+	if err := install.WriteBlockedScripts(meta.Pwd()); err != nil {
+		logger.Warnf("failed to record skipped lifecycle scripts: %s", err)
+	}
+	blocked := install.BlockedScripts()
+	if len(blocked) == 0 {
+		return
+	}
+	logger.Warnf("skipped lifecycle scripts of %d untrusted dependencies: %s\nrun `jmod approve-builds` to allow them", len(blocked), strings.Join(blocked, ", "))
+}
+
+func init() {
+	addPlatformFlags(approveBuildsCmd)
+	addEngineFlags(approveBuildsCmd)
+	rootCmd.AddCommand(approveBuildsCmd)
+}
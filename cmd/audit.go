@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/tsukinoko-kun/jmod/audit"
+	"github.com/tsukinoko-kun/jmod/config"
+	"github.com/tsukinoko-kun/jmod/meta"
+	"github.com/tsukinoko-kun/jmod/utils"
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Check installed dependencies for known vulnerabilities",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var ignore []string
+		level := "low"
+		if settings := config.LoadSettings(meta.Pwd()); settings.Audit != nil {
+			ignore = settings.Audit.Ignore
+			if settings.Audit.Level != "" {
+				level = settings.Audit.Level
+			}
+		}
+		if cmd.Flags().Changed("audit-level") {
+			level = utils.Must(cmd.Flags().GetString("audit-level"))
+		}
+		minSeverity, err := audit.ParseSeverity(level)
+		if err != nil {
+			return err
+		}
+
+		findings, err := audit.Run(cmd.Context(), meta.Pwd(), ignore)
+		if err != nil {
+			return fmt.Errorf("audit: %w", err)
+		}
+
+		if utils.Must(cmd.Flags().GetBool("json")) {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if findings == nil {
+				findings = []audit.Finding{}
+			}
+			if err := enc.Encode(findings); err != nil {
+				return err
+			}
+		} else {
+			printFindings(findings)
+		}
+
+		failing := 0
+		for _, f := range findings {
+			if f.Severity >= minSeverity {
+				failing++
+			}
+		}
+		if failing > 0 {
+			return fmt.Errorf("found %d vulnerabilities with severity %s or higher", failing, minSeverity)
+		}
+		return nil
+	},
+}
+
+func printFindings(findings []audit.Finding) {
+	if len(findings) == 0 {
+		fmt.Println("no known vulnerabilities found")
+		return
+	}
+	for i, f := range findings {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%s: %s@%s\n", f.Severity, f.Package, f.Version)
+		fmt.Printf("  %s\n", f.Advisory.Title)
+		if f.Advisory.Url != "" {
+			fmt.Printf("  %s\n", f.Advisory.Url)
+		}
+		fmt.Printf("  vulnerable versions: %s\n", f.Advisory.VulnerableVersions)
+		for _, chain := range f.Chains {
+			fmt.Printf("  via %s\n", chain.String())
+		}
+	}
+}
+
+func init() {
+	auditCmd.Flags().String("audit-level", "low", "minimum severity that makes the audit fail (info, low, moderate, high, critical)")
+	auditCmd.Flags().Bool("json", false, "print the findings as JSON")
+	rootCmd.AddCommand(auditCmd)
+}
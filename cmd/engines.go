@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"github.com/Masterminds/semver/v3"
+	"github.com/spf13/cobra"
+	"github.com/tsukinoko-kun/jmod/config"
+	"github.com/tsukinoko-kun/jmod/meta"
+	"github.com/tsukinoko-kun/jmod/registry"
+	"github.com/tsukinoko-kun/jmod/scriptsrunner"
+	"github.com/tsukinoko-kun/jmod/utils"
+)
+
+func addEngineFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("engine-strict", false, "fail if a dependency's engines field is not satisfied by the current runtime")
+}
+
+// applyEngineFlags detects the runtime versions and configures how the engines field of dependencies is checked.
+func applyEngineFlags(cmd *cobra.Command) {
+	settings := config.LoadSettings(meta.Pwd())
+	registry.EngineStrict = settings.EngineStrict || utils.Must(cmd.Flags().GetBool("engine-strict"))
+	registry.PreferCompatibleEngines = settings.PreferCompatibleEngines
+
+	registry.EngineVersions["jmod"] = semver.New(uint64(meta.Version), 0, 0, "", "")
+	registry.EngineDetectors["node"] = func() *semver.Version {
+		return detectedVersion(scriptsrunner.NodeVersion())
+	}
+	// spawning npm is slow, it only runs if a package constrains the npm version
+	registry.EngineDetectors["npm"] = func() *semver.Version {
+		return detectedVersion(scriptsrunner.NpmVersion())
+	}
+}
+
+func detectedVersion(v string, ok bool) *semver.Version {
+	if !ok {
+		return nil
+	}
+	version, err := semver.NewVersion(v)
+	if err != nil {
+		return nil
+	}
+	return version
+}
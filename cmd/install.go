@@ -1,9 +1,20 @@
 package cmd
 
 import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
 	"github.com/spf13/cobra"
+	"github.com/tsukinoko-kun/disize"
+	"github.com/tsukinoko-kun/jmod/config"
 	"github.com/tsukinoko-kun/jmod/install"
+	"github.com/tsukinoko-kun/jmod/logger"
 	"github.com/tsukinoko-kun/jmod/meta"
+	"github.com/tsukinoko-kun/jmod/registry"
 	"github.com/tsukinoko-kun/jmod/statusui"
 	"github.com/tsukinoko-kun/jmod/utils"
 )
@@ -22,13 +33,137 @@ var installCmd = &cobra.Command{
 		}
 		defer statusui.Stop()
 
-		ctx := cmd.Context()
-		install.Run(ctx, meta.Pwd(), utils.Must(cmd.Flags().GetBool("ignore-scripts")), true, true, nil)
-		return nil
+		applyPlatformFlags(cmd)
+		applyEngineFlags(cmd)
+		opts, err := installOptions(cmd)
+		if err != nil {
+			return err
+		}
+		return runInstall(cmd.Context(), opts)
 	},
 }
 
+// installOptions returns the install options configured in the project root and the flags of cmd.
+func installOptions(cmd *cobra.Command) (install.Options, error) {
+	overrides, err := config.LoadOverrides(meta.Pwd())
+	if err != nil {
+		return install.Options{}, err
+	}
+	settings := config.LoadSettings(meta.Pwd())
+	opts := install.Options{
+		Dev:                 true,
+		Optional:            true,
+		Overrides:           overrides,
+		Patches:             config.LoadPatches(meta.Pwd()),
+		TrustedDependencies: config.LoadTrustedDependencies(meta.Pwd()),
+		Sandbox:             settings.Sandbox,
+		ScriptConcurrency:   settings.ScriptConcurrency,
+	}
+	if cmd.Flags().Lookup("ignore-scripts") != nil {
+		opts.IgnoreScripts = utils.Must(cmd.Flags().GetBool("ignore-scripts"))
+	}
+	if cmd.Flags().Changed("script-concurrency") {
+		opts.ScriptConcurrency = utils.Must(cmd.Flags().GetInt("script-concurrency"))
+	}
+	if cmd.Flags().Lookup("keep-going") != nil {
+		registry.KeepGoing = utils.Must(cmd.Flags().GetBool("keep-going"))
+	}
+	return opts, nil
+}
+
+// runInstall installs the project at the working directory and reports the result.
+func runInstall(ctx context.Context, opts install.Options) error {
+	root := meta.Pwd()
+	before, err := install.ReadSnapshot(root)
+	if err != nil {
+		logger.Warnf("failed to read the previous install state: %s", err)
+		before = install.Snapshot{}
+	}
+	start := time.Now()
+	install.Run(ctx, root, opts, nil)
+	if ctx.Err() == nil {
+		after := install.TakeSnapshot(root)
+		if err := install.WriteSnapshot(root, after); err != nil {
+			logger.Warnf("failed to write the install state: %s", err)
+		}
+		if err := install.WriteOverrides(root); err != nil {
+			logger.Warnf("failed to write the applied overrides: %s", err)
+		}
+		reportSummary(install.GetSummary(), time.Since(start), install.Diff(before, after), len(after) > 1)
+	}
+	reportBlockedScripts()
+	return reportFailures()
+}
+
+// reportSummary prints the counts and timings of the install and the changed direct dependencies.
+func reportSummary(summary install.Summary, took time.Duration, diff []install.ModuleChanges, showModules bool) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Resolved %d packages: %d downloaded (%s), %d from cache\n",
+		summary.Resolved, summary.Downloaded, disize.Size(summary.Bytes).String(), summary.Cached)
+	if summary.Scripts > 0 {
+		fmt.Fprintf(&b, "Ran %d lifecycle scripts\n", summary.Scripts)
+	}
+	phases := make([]string, len(summary.Phases))
+	for i, phase := range summary.Phases {
+		phases[i] = fmt.Sprintf("%s %s", phase.Name, phase.Duration.Round(time.Millisecond))
+	}
+	fmt.Fprintf(&b, "Done in %s (%s)\n", took.Round(time.Millisecond), strings.Join(phases, ", "))
+	for _, module := range diff {
+		b.WriteString("\n")
+		if showModules {
+			fmt.Fprintf(&b, "%s:\n", module.Module)
+		}
+		for _, change := range module.Changes {
+			switch {
+			case change.From == "":
+				fmt.Fprintf(&b, "+ %s %s\n", change.Name, change.To)
+			case change.To == "":
+				fmt.Fprintf(&b, "- %s %s\n", change.Name, change.From)
+			default:
+				fmt.Fprintf(&b, "~ %s %s → %s\n", change.Name, change.From, change.To)
+			}
+		}
+	}
+	// log line by line, styling a multi-line message pads every line to the same width
+	for line := range strings.Lines(b.String()) {
+		logger.Infof("%s", strings.TrimSuffix(line, "\n"))
+	}
+}
+
+// reportFailures prints every failed package of the install grouped by the kind of failure.
+func reportFailures() error {
+	failures := registry.Failures()
+	if len(failures) == 0 {
+		return nil
+	}
+	slices.SortStableFunc(failures, func(a, b *registry.Failure) int {
+		return cmp.Compare(a.Kind, b.Kind)
+	})
+	var b strings.Builder
+	for i, f := range failures {
+		if i == 0 || failures[i-1].Kind != f.Kind {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(&b, "%s failures:\n", f.Kind)
+		}
+		fmt.Fprintf(&b, "  %s\n", f.Error())
+	}
+	logger.Errorf("%s", strings.TrimSuffix(b.String(), "\n"))
+	// the failures are reported, the returned error only sets the exit code
+	rootCmd.SilenceErrors = true
+	return fmt.Errorf("%d packages failed to install", len(failures))
+}
+
+func addInstallFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("ignore-scripts", false, "Ignore scripts in package.json")
+	cmd.Flags().Int("script-concurrency", 0, "maximum number of packages running lifecycle scripts at once (default number of CPUs)")
+	cmd.Flags().Bool("keep-going", false, "install everything possible and report all failures at the end instead of stopping at the first")
+}
+
 func init() {
-	installCmd.Flags().Bool("ignore-scripts", false, "Ignore scripts in package.json")
+	addInstallFlags(installCmd)
+	addPlatformFlags(installCmd)
+	addEngineFlags(installCmd)
 	rootCmd.AddCommand(installCmd)
 }
@@ -2,9 +2,11 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/tsukinoko-kun/jmod/config"
+	"github.com/tsukinoko-kun/jmod/install"
 	"github.com/tsukinoko-kun/jmod/meta"
 )
 
@@ -12,6 +14,10 @@ var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all dependencies",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		overrides, err := config.LoadOverrides(meta.Pwd())
+		if err != nil {
+			return err
+		}
 		mods := config.FindSubMods(meta.Pwd())
 		for i, mod := range mods {
 			if i > 0 {
@@ -19,16 +25,36 @@ var listCmd = &cobra.Command{
 			}
 			fmt.Println("module:", mod.TypedData.GetFileLocation())
 			for dep, version := range mod.TypedData.NpmDependencies {
-				fmt.Printf("  %s@%s\n", dep, version)
+				printDependency(overrides, dep, version)
 			}
 			for dep, version := range mod.TypedData.NpmDevDependencies {
-				fmt.Printf("  %s@%s\n", dep, version)
+				printDependency(overrides, dep, version)
+			}
+		}
+
+		applied, err := install.ReadOverrides(meta.Pwd())
+		if err != nil {
+			return err
+		}
+		if len(applied) != 0 {
+			fmt.Println()
+			fmt.Println("overrides applied by the last install:")
+			for _, o := range applied {
+				fmt.Printf("  %s@%s (%s)\n", o.Name, o.Override.String(), strings.Join(o.Chain, " > "))
 			}
 		}
 		return nil
 	},
 }
 
+func printDependency(overrides *config.Overrides, dep string, version string) {
+	if o, ok := overrides.Lookup(dep, version); ok {
+		fmt.Printf("  %s@%s\n", dep, o.String())
+		return
+	}
+	fmt.Printf("  %s@%s\n", dep, version)
+}
+
 func init() {
 	rootCmd.AddCommand(listCmd)
 }
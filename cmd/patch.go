@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/spf13/cobra"
+	"github.com/tsukinoko-kun/jmod/config"
+	"github.com/tsukinoko-kun/jmod/meta"
+	"github.com/tsukinoko-kun/jmod/patch"
+	"github.com/tsukinoko-kun/jmod/registry"
+	"github.com/tsukinoko-kun/jmod/statusui"
+)
+
+var patchCmd = &cobra.Command{
+	Use:   "patch",
+	Short: "Prepare a package for patching",
+	Long:  "Extracts an editable copy of an installed package to a temporary directory. Commit your changes with jmod patch-commit.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return cmd.Help()
+		}
+
+		name, version := splitPackageVersion(args[0])
+		mod := cmd.Flag("mod").Value.String()
+		original, version, err := findPristinePackage(cmd, filepath.Join(meta.Pwd(), mod), name, version)
+		if err != nil {
+			return err
+		}
+
+		dir, err := os.MkdirTemp("", "jmod-patch-*")
+		if err != nil {
+			return err
+		}
+		if err := patch.CopyDir(original, dir); err != nil {
+			return fmt.Errorf("copy %s: %w", original, err)
+		}
+		// continue editing an existing patch
+		if patchFile, ok := config.LoadPatches(meta.Pwd()).Lookup(name, version); ok {
+			diff, err := os.ReadFile(patchFile)
+			if err != nil {
+				return fmt.Errorf("read patch: %w", err)
+			}
+			if err := patch.Apply(dir, diff); err != nil {
+				return fmt.Errorf("apply existing patch %s: %w", patchFile, err)
+			}
+		}
+		if err := patch.WriteMetadata(dir, patch.Metadata{Name: name, Version: version, Original: original}); err != nil {
+			return err
+		}
+
+		fmt.Printf("You can now edit %s@%s at:\n\n  %s\n\nTo commit your changes, run:\n\n  jmod patch-commit %s\n", name, version, dir, dir)
+		return nil
+	},
+}
+
+// splitPackageVersion splits name@version, the version is empty if not specified.
+func splitPackageVersion(identifier string) (name string, version string) {
+	// scoped package would have @ as the first character
+	if lastAtIndex := strings.LastIndex(identifier, "@"); lastAtIndex > 0 {
+		return identifier[:lastAtIndex], identifier[lastAtIndex+1:]
+	}
+	return identifier, ""
+}
+
+// findPristinePackage returns the unmodified cache location of a package.
+// Without a version, the version installed in the module is used.
+func findPristinePackage(cmd *cobra.Command, modDir string, name string, version string) (string, string, error) {
+	if version == "" {
+		installed, err := filepath.EvalSymlinks(filepath.Join(modDir, "node_modules", name))
+		if err != nil {
+			return "", "", fmt.Errorf("%s is not installed in %s, run jmod install first or specify a version", name, modDir)
+		}
+		pj, err := config.GetPackageJsonForLifecycle(filepath.Join(installed, "package.json"))
+		if err != nil {
+			return "", "", err
+		}
+		if pj.Version == nil {
+			return "", "", fmt.Errorf("%s has no version", installed)
+		}
+		version = *pj.Version
+	}
+
+	constraint, err := semver.NewConstraint("=" + version)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid version %q: %w", version, err)
+	}
+	if ok, cachedLocation := registry.CacheHas(cmd.Context(), "npm", name, constraint); ok {
+		return cachedLocation, version, nil
+	}
+
+	if err := statusui.Start(); err != nil {
+		return "", "", err
+	}
+	defer statusui.Stop()
+	resolver, err := registry.Npm_Resolve(cmd.Context(), name, constraint)
+	if err != nil {
+		return "", "", fmt.Errorf("resolve %s@%s: %w", name, version, err)
+	}
+	cachedLocation, err := registry.CachePut(cmd.Context(), "npm", resolver)
+	if err != nil {
+		return "", "", fmt.Errorf("download %s@%s: %w", name, version, err)
+	}
+	return cachedLocation, version, nil
+}
+
+func init() {
+	rootCmd.AddCommand(patchCmd)
+	patchCmd.Flags().String("mod", ".", "module the package is installed in")
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/tsukinoko-kun/jmod/config"
+	"github.com/tsukinoko-kun/jmod/logger"
+	"github.com/tsukinoko-kun/jmod/meta"
+	"github.com/tsukinoko-kun/jmod/patch"
+	"github.com/tsukinoko-kun/jmod/statusui"
+)
+
+var patchCommitCmd = &cobra.Command{
+	Use:   "patch-commit",
+	Short: "Create a patch from a directory prepared by jmod patch",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return cmd.Help()
+		}
+
+		dir := args[0]
+		md, err := patch.ReadMetadata(dir)
+		if err != nil {
+			return err
+		}
+		diff, err := patch.DiffDirs(md.Original, dir)
+		if err != nil {
+			return err
+		}
+		if len(diff) == 0 {
+			return fmt.Errorf("no changes in %s", dir)
+		}
+
+		c, err := config.Load(meta.Pwd())
+		if err != nil {
+			return err
+		}
+		patchesDir := filepath.Join(filepath.Dir(c.TypedData.GetFileLocation()), "patches")
+		if err := os.MkdirAll(patchesDir, 0o755); err != nil {
+			return err
+		}
+		fileName := patch.FileName(md.Name, md.Version)
+		if err := os.WriteFile(filepath.Join(patchesDir, fileName), diff, 0o644); err != nil {
+			return err
+		}
+
+		if c.TypedData.PatchedDependencies == nil {
+			c.TypedData.PatchedDependencies = map[string]string{}
+		}
+		c.TypedData.PatchedDependencies[fmt.Sprintf("%s@%s", md.Name, md.Version)] = "patches/" + fileName
+		if err := config.Write(c); err != nil {
+			return err
+		}
+		logger.Printf("patch for %s@%s written to patches/%s (%s)", md.Name, md.Version, fileName, patch.Hash(diff))
+
+		if err := statusui.Start(); err != nil {
+			return err
+		}
+		defer statusui.Stop()
+
+		opts, err := installOptions(cmd)
+		if err != nil {
+			return err
+		}
+		return runInstall(cmd.Context(), opts)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(patchCommitCmd)
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/tsukinoko-kun/jmod/registry"
+	"github.com/tsukinoko-kun/jmod/utils"
+)
+
+func addPlatformFlags(cmd *cobra.Command) {
+	cmd.Flags().String("os", "", "install packages for this operating system instead of the host (e.g. linux, darwin, win32)")
+	cmd.Flags().String("cpu", "", "install packages for this CPU architecture instead of the host (e.g. x64, arm64)")
+	cmd.Flags().String("libc", "", "install packages for this libc instead of the host (glibc or musl)")
+}
+
+// applyPlatformFlags overrides registry.TargetPlatform with the values passed via --os, --cpu and --libc.
+func applyPlatformFlags(cmd *cobra.Command) {
+	target := registry.TargetPlatform
+	if targetOS := utils.Must(cmd.Flags().GetString("os")); targetOS != "" {
+		target.OS = targetOS
+		if targetOS != "linux" {
+			target.Libc = ""
+		} else if target.Libc == "" {
+			// cross-installing for Linux from another OS, assume the common case
+			target.Libc = "glibc"
+		}
+	}
+	if cpu := utils.Must(cmd.Flags().GetString("cpu")); cpu != "" {
+		target.CPU = cpu
+	}
+	if libc := utils.Must(cmd.Flags().GetString("libc")); libc != "" {
+		target.Libc = libc
+	}
+	registry.TargetPlatform = target
+}
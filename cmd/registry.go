@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/tsukinoko-kun/jmod/config"
+	"github.com/tsukinoko-kun/jmod/logger"
+	"github.com/tsukinoko-kun/jmod/meta"
+	"github.com/tsukinoko-kun/jmod/registry"
+)
+
+// applyRegistrySettings configures the registry endpoints, network limits, retries and signature verification from the project settings
+// and the proxy and TLS options from .npmrc.
+func applyRegistrySettings() error {
+	applyNpmrcNetwork()
+	settings := config.LoadSettings(meta.Pwd())
+	if settings.Registry != "" {
+		registry.NpmRegistry = settings.Registry
+	}
+	if settings.SignatureKeys != "" {
+		registry.NpmKeysURL = settings.SignatureKeys
+	}
+	if settings.MaxSockets > 0 {
+		registry.MaxRequestsPerHost = settings.MaxSockets
+	}
+	if settings.FetchRetries != nil {
+		registry.FetchRetries = max(*settings.FetchRetries, 0)
+	}
+	if settings.FetchRetryMinTimeout > 0 {
+		registry.FetchRetryMinDelay = time.Duration(settings.FetchRetryMinTimeout) * time.Millisecond
+	}
+	if settings.FetchRetryMaxTimeout > 0 {
+		registry.FetchRetryMaxDelay = time.Duration(settings.FetchRetryMaxTimeout) * time.Millisecond
+	}
+	mode, err := registry.ParseSignatureMode(settings.Signatures)
+	if err != nil {
+		return err
+	}
+	registry.Signatures = mode
+	return nil
+}
+
+// applyNpmrcNetwork configures the proxy, certificate authorities and strict-ssl like npm does.
+// Invalid options only produce warnings so commands that don't reach the registry keep working,
+// invalid certificate authorities fall back to the system certificate pool.
+func applyNpmrcNetwork() {
+	rc := config.LoadNpmrc(meta.Pwd())
+	cfg := registry.NetworkConfig{StrictSSL: true}
+	cfg.Proxy, _ = rc.Get("proxy")
+	cfg.HTTPSProxy, _ = rc.Get("https-proxy")
+	if noProxy, ok := rc.Get("noproxy"); ok {
+		cfg.NoProxy = noProxy
+	} else {
+		cfg.NoProxy, _ = rc.Get("no-proxy")
+	}
+	cfg.CA = rc.All("ca")
+	cfg.CAFile, _ = rc.Get("cafile")
+	if strictSSL, ok := rc.Get("strict-ssl"); ok {
+		if v, err := strconv.ParseBool(strictSSL); err != nil {
+			logger.Warnf("ignoring invalid strict-ssl %q in .npmrc: %s", strictSSL, err)
+		} else {
+			cfg.StrictSSL = v
+		}
+	}
+	err := registry.ConfigureNetwork(cfg)
+	if err != nil && (len(cfg.CA) != 0 || cfg.CAFile != "") {
+		logger.Warnf("ignoring the certificate authorities of .npmrc: %s", err)
+		cfg.CA, cfg.CAFile = nil, ""
+		err = registry.ConfigureNetwork(cfg)
+	}
+	if err != nil {
+		logger.Warnf("failed to configure the network, using the defaults: %s", err)
+	}
+}
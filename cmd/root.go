@@ -6,6 +6,7 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/tsukinoko-kun/jmod/logger"
+	"github.com/tsukinoko-kun/jmod/statusui"
 )
 
 var rootCmd = &cobra.Command{
@@ -13,8 +14,14 @@ var rootCmd = &cobra.Command{
 	SilenceUsage:      true,
 	Use:               "jmod",
 	Short:             "The actually good package manager for JavaScript because JS devs are insane",
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 		logger.Verbose = flagVerbose
+		reporter, err := statusui.ParseReporter(flagReporter)
+		if err != nil {
+			return err
+		}
+		statusui.Mode = reporter
+		return applyRegistrySettings()
 	},
 }
 
@@ -25,8 +32,12 @@ func Execute(ctx context.Context) {
 	}
 }
 
-var flagVerbose bool
+var (
+	flagVerbose  bool
+	flagReporter string
+)
 
 func init() {
 	rootCmd.PersistentFlags().BoolVarP(&flagVerbose, "verbose", "v", false, "verbose output")
+	rootCmd.PersistentFlags().StringVar(&flagReporter, "reporter", string(statusui.ReporterAuto), "output format: auto, tui, line or ndjson")
 }
@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/tsukinoko-kun/jmod/scriptsrunner"
+	"github.com/tsukinoko-kun/jmod/utils"
+)
+
+// sandboxExecCmd is started by the scripts runner to restrict itself before executing a dependency script.
+var sandboxExecCmd = &cobra.Command{
+	Use:    scriptsrunner.SandboxCommand + " [--write path]... -- command [args...]",
+	Hidden: true,
+	Args:   cobra.MinimumNArgs(1),
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return scriptsrunner.SandboxExec(utils.Must(cmd.Flags().GetStringArray("write")), args)
+	},
+}
+
+func init() {
+	sandboxExecCmd.Flags().StringArray("write", nil, "writable path")
+	rootCmd.AddCommand(sandboxExecCmd)
+}
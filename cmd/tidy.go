@@ -4,13 +4,19 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/tsukinoko-kun/jmod/meta"
 	"github.com/tsukinoko-kun/jmod/tidy"
+	"github.com/tsukinoko-kun/jmod/utils"
 )
 
 var tidyCmd = &cobra.Command{
 	Use:   "tidy",
-	Short: "A brief description of your command",
+	Short: "Add missing dependencies and report or remove unused ones",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if err := tidy.Run(meta.Pwd()); err != nil {
+		opts := tidy.Options{
+			Prune:  utils.Must(cmd.Flags().GetBool("prune")),
+			DryRun: utils.Must(cmd.Flags().GetBool("dry-run")),
+			Check:  utils.Must(cmd.Flags().GetBool("check")),
+		}
+		if err := tidy.Run(meta.Pwd(), opts); err != nil {
 			return err
 		}
 		return nil
@@ -19,4 +25,7 @@ var tidyCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(tidyCmd)
+	tidyCmd.Flags().Bool("prune", false, "remove dependencies that are not used")
+	tidyCmd.Flags().Bool("dry-run", false, "report the changes without writing package.json")
+	tidyCmd.Flags().Bool("check", false, "fail if package.json is not tidy, without writing it")
 }
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/tsukinoko-kun/jmod/config"
+	"github.com/tsukinoko-kun/jmod/meta"
+	"github.com/tsukinoko-kun/jmod/utils"
+)
+
+type workspaceModule struct {
+	Name string `json:"name,omitempty"`
+	Path string `json:"path"`
+}
+
+var workspacesCmd = &cobra.Command{
+	Use:   "workspaces",
+	Short: "List the modules of the workspace",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		root := meta.Pwd()
+		modules := []workspaceModule{}
+		for _, mod := range config.FindSubMods(root) {
+			dir := filepath.Dir(mod.TypedData.GetFileLocation())
+			m := workspaceModule{Path: filepath.ToSlash(utils.Must(filepath.Rel(root, dir)))}
+			if pj, err := config.GetPackageJsonForLifecycle(mod.TypedData.GetFileLocation()); err == nil && pj.Name != nil {
+				m.Name = *pj.Name
+			}
+			modules = append(modules, m)
+		}
+
+		if utils.Must(cmd.Flags().GetBool("json")) {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(modules)
+		}
+		for _, m := range modules {
+			name := m.Name
+			if name == "" {
+				name = "(unnamed)"
+			}
+			fmt.Printf("%s\t%s\n", name, m.Path)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(workspacesCmd)
+	workspacesCmd.Flags().Bool("json", false, "print the modules as JSON")
+}
@@ -13,7 +13,6 @@ import (
 	"time"
 
 	"github.com/Masterminds/semver/v3"
-	"github.com/tsukinoko-kun/jmod/ignore"
 	"github.com/tsukinoko-kun/jmod/logger"
 	"github.com/tsukinoko-kun/jmod/meta"
 	"github.com/tsukinoko-kun/jmod/registry"
@@ -31,6 +30,11 @@ type Mod struct {
 	NpmDependencies         map[string]string `json:"dependencies"`
 	NpmDevDependencies      map[string]string `json:"devDependencies"`
 	NpmOptionalDependencies map[string]string `json:"optionalDependencies"`
+	NpmOverrides            map[string]any    `json:"overrides,omitempty"`
+	YarnResolutions         map[string]string `json:"resolutions,omitempty"`
+	PatchedDependencies     map[string]string `json:"patchedDependencies,omitempty"`
+	NpmTrustedDependencies  []string          `json:"trustedDependencies,omitempty"`
+	Jmod                    *Settings         `json:"jmod,omitempty"`
 }
 
 func (m *Mod) GetFileLocation() string {
@@ -41,6 +45,14 @@ type (
 	ResolvedDependency struct {
 		PackageName    string
 		CachedLocation string
+		// RegistryName is the name of the package in the registry, it differs from PackageName for npm: aliases
+		RegistryName string
+		// Version is the resolved version, empty for local dependencies
+		Version string
+		// Overrides apply to the dependencies of this package
+		Overrides *Overrides
+		// Override is set if the requested version was replaced by an override
+		Override *Override
 	}
 
 	ResolvedDependencyBin struct {
@@ -53,6 +65,8 @@ type packageJsonForLifecycle struct {
 	Name    *string            `json:"name,omitempty"`
 	Version *string            `json:"version,omitempty"`
 	Scripts *map[string]string `json:"scripts,omitempty"`
+	// Gypfile set to false disables the implicit node-gyp build of a package with a binding.gyp
+	Gypfile *bool `json:"gypfile,omitempty"`
 }
 
 func (p *packageJsonForLifecycle) Identifier() string {
@@ -164,21 +178,57 @@ func ResolveBins(ctx context.Context, modulePath string) ([]ResolvedDependencyBi
 	return bins, nil
 }
 
-func runGo(version string, packageName string, constPackageName string, ctx context.Context, ch chan<- ResolvedDependency, m *Mod, optional bool, dependencyChain registry.DependencyChain) func() {
+// BinNames returns the names of the executables the package at modulePath provides.
+func BinNames(modulePath string) ([]string, error) {
+	pj, err := getPackageJsonForBin(filepath.Join(modulePath, "package.json"))
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(pj.Bin))
+	for name := range pj.Bin {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func runGo(version string, packageName string, constPackageName string, ctx context.Context, ch chan<- ResolvedDependency, m *Mod, optional bool, dependencyChain registry.DependencyChain, overrides *Overrides) func() {
 	return func() {
+		var appliedOverride *Override
+		lookupVersion := version
+		if overrides.NeedsVersion(packageName) {
+			if resolvedVersion, ok := resolveVersion(ctx, packageName, version); ok {
+				lookupVersion = resolvedVersion
+			}
+		}
+		if o, ok := overrides.Lookup(packageName, lookupVersion); ok {
+			o.From = version
+			logger.Printf("override %s@%s (%s)", packageName, o.String(), dependencyChain.String())
+			appliedOverride = &o
+			version = o.To
+		}
+		resolved := func(cachedLocation string, resolvedVersion string) ResolvedDependency {
+			return ResolvedDependency{
+				PackageName:    constPackageName,
+				CachedLocation: cachedLocation,
+				RegistryName:   packageName,
+				Version:        resolvedVersion,
+				Overrides:      overrides.Descend(packageName, resolvedVersion),
+				Override:       appliedOverride,
+			}
+		}
+
 		if strings.HasPrefix(version, "file:") || strings.HasPrefix(version, "./") || strings.HasPrefix(version, "../") || strings.HasPrefix(version, "/") {
 			absPath := filepath.Join(filepath.Dir(m.GetFileLocation()), strings.TrimPrefix(version, "file:"))
 			if _, err := os.Stat(absPath); err == nil {
 				select {
-				case ch <- ResolvedDependency{constPackageName, absPath}:
+				case ch <- resolved(absPath, ""):
 				case <-ctx.Done():
 				}
 			} else {
-				err = dependencyChain.Err(err)
 				if optional {
-					logger.Printf("local file dep %s not found for mod %s: %v", version, m.GetFileLocation(), err)
+					logger.Printf("local file dep %s not found for mod %s: %v", version, m.GetFileLocation(), dependencyChain.Err(err))
 				} else {
-					meta.CancelCause(fmt.Errorf("local file dep %s not found for mod %s: %w", version, m.GetFileLocation(), err))
+					registry.Fail(registry.FailureResolution, constPackageName, dependencyChain, fmt.Errorf("local file dep %s not found for mod %s: %w", version, m.GetFileLocation(), err))
 				}
 			}
 			return
@@ -186,21 +236,21 @@ func runGo(version string, packageName string, constPackageName string, ctx cont
 			if optional {
 				logger.Printf("TODO git %s", version)
 			} else {
-				meta.CancelCause(fmt.Errorf("TODO git %s %s", version, dependencyChain.String()))
+				registry.Fail(registry.FailureResolution, constPackageName, dependencyChain, fmt.Errorf("git dependencies are not supported yet: %s", version))
 			}
 			return
 		} else if strings.HasPrefix(version, "github:") {
 			if optional {
 				logger.Printf("TODO github %s", version)
 			} else {
-				meta.CancelCause(fmt.Errorf("TODO github %s %s", version, dependencyChain.String()))
+				registry.Fail(registry.FailureResolution, constPackageName, dependencyChain, fmt.Errorf("github dependencies are not supported yet: %s", version))
 			}
 			return
 		} else if pck, ok := strings.CutPrefix(version, "jsr:"); ok {
 			if optional {
 				logger.Printf("TODO jsr %s", pck)
 			} else {
-				meta.CancelCause(fmt.Errorf("TODO jsr %s %s", pck, dependencyChain.String()))
+				registry.Fail(registry.FailureResolution, constPackageName, dependencyChain, fmt.Errorf("jsr dependencies are not supported yet: %s", pck))
 			}
 			return
 		} else if alias, ok := strings.CutPrefix(version, "npm:"); ok {
@@ -219,11 +269,10 @@ func runGo(version string, packageName string, constPackageName string, ctx cont
 				var newErr error
 				versionConstraint, newErr = semver.NewConstraint(tagVersion)
 				if newErr != nil {
-					err = dependencyChain.Err(err)
 					if optional {
-						logger.Printf("invalid version constraint %s for %s in %s, skipping: %v", version, packageName, m.GetFileLocation(), err)
+						logger.Printf("invalid version constraint %s for %s in %s, skipping: %v", version, packageName, m.GetFileLocation(), dependencyChain.Err(newErr))
 					} else {
-						meta.CancelCause(fmt.Errorf("invalid version constraint %s for %s in %s, skipping: %w", version, packageName, m.GetFileLocation(), err))
+						registry.Fail(registry.FailureResolution, packageName, dependencyChain, fmt.Errorf("invalid version constraint %s for %s in %s: %w", version, packageName, m.GetFileLocation(), newErr))
 					}
 					return
 				}
@@ -231,26 +280,31 @@ func runGo(version string, packageName string, constPackageName string, ctx cont
 				if optional {
 					logger.Printf("invalid version constraint %s for %s in %s, skipping (%s)", version, packageName, m.GetFileLocation(), dependencyChain.String())
 				} else {
-					meta.CancelCause(fmt.Errorf("invalid version constraint %s for %s in %s, skipping (%s)", version, packageName, m.GetFileLocation(), dependencyChain.String()))
+					registry.Fail(registry.FailureResolution, packageName, dependencyChain, fmt.Errorf("invalid version constraint %s for %s in %s: %w", version, packageName, m.GetFileLocation(), err))
 				}
 				return
 			}
 		}
-		if ok, cachedLocation := registry.CacheHas("npm", packageName, versionConstraint); ok {
+		if ok, cachedLocation := registry.CacheHas(ctx, "npm", packageName, versionConstraint); ok {
+			cachedVersion := filepath.Base(filepath.Dir(cachedLocation))
+			registry.RecordResolved(packageName, cachedVersion)
 			select {
-			case ch <- ResolvedDependency{constPackageName, cachedLocation}:
+			case ch <- resolved(cachedLocation, cachedVersion):
 			case <-ctx.Done():
 			}
 			return
 		}
 		resolver, err := registry.Npm_Resolve(ctx, packageName, versionConstraint)
 		if err != nil {
+			if errors.Is(err, registry.ErrUnsupportedPlatform) && optional {
+				logger.Printf("skipping optional dependency %s@%s: %s", packageName, version, err)
+				return
+			}
 			if !errors.Is(err, context.Canceled) {
-				err = dependencyChain.Err(err)
 				if optional {
-					logger.Printf("failed to resolve %s@%s: %s", packageName, version, err)
+					logger.Printf("failed to resolve %s@%s: %s", packageName, version, dependencyChain.Err(err))
 				} else {
-					meta.CancelCause(fmt.Errorf("failed to resolve %s@%s: %s", packageName, version, err))
+					registry.Fail(registry.FailureResolution, packageName, dependencyChain, fmt.Errorf("failed to resolve %s@%s: %w", packageName, version, err))
 				}
 			}
 			return
@@ -259,44 +313,63 @@ func runGo(version string, packageName string, constPackageName string, ctx cont
 		cachedLocation, err := registry.CachePut(ctx, "npm", resolver)
 		if err != nil {
 			if !errors.Is(err, context.Canceled) {
-				err = dependencyChain.Err(err)
 				if optional {
-					logger.Printf("failed to cache %s@%s: %s", packageName, resolver.GetVersion(), err)
+					logger.Printf("failed to cache %s@%s: %s", packageName, resolver.GetVersion(), dependencyChain.Err(err))
 				} else {
-					meta.CancelCause(fmt.Errorf("failed to cache %s@%s: %s", packageName, resolver.GetVersion(), err))
+					registry.Fail(registry.DownloadFailureKind(err), packageName, dependencyChain, fmt.Errorf("failed to cache %s@%s: %w", packageName, resolver.GetVersion(), err))
 				}
 			}
 			return
 		}
 		logger.Printf("downloaded %s in %s", resolver.String(), time.Since(start))
+		registry.RecordResolved(packageName, resolver.GetVersion())
 		select {
-		case ch <- ResolvedDependency{constPackageName, cachedLocation}:
+		case ch <- resolved(cachedLocation, resolver.GetVersion()):
 		case <-ctx.Done():
 		}
 	}
 }
 
-func (m *Mod) ResolveDependenciesDeep(ctx context.Context, dev bool, optional bool, dependencyChain registry.DependencyChain) <-chan ResolvedDependency {
+// resolveVersion returns the version a range of an npm package resolves to, preferring cached versions.
+func resolveVersion(ctx context.Context, packageName string, version string) (string, bool) {
+	if _, err := semver.NewVersion(version); err == nil {
+		return version, true
+	}
+	c, err := semver.NewConstraint(version)
+	if err != nil {
+		return "", false
+	}
+	if ok, cachedLocation := registry.CacheHas(ctx, "npm", packageName, c); ok {
+		return filepath.Base(filepath.Dir(cachedLocation)), true
+	}
+	resolver, err := registry.Npm_Resolve(ctx, packageName, c)
+	if err != nil {
+		return "", false
+	}
+	return resolver.GetVersion(), true
+}
+
+func (m *Mod) ResolveDependenciesDeep(ctx context.Context, dev bool, optional bool, dependencyChain registry.DependencyChain, overrides *Overrides) <-chan ResolvedDependency {
 	wg := sync.WaitGroup{}
 
 	ch := make(chan ResolvedDependency, 16)
 
 	for packageName, version := range m.NpmDependencies {
 		constPackageName := packageName
-		wg.Go(runGo(version, packageName, constPackageName, ctx, ch, m, false, dependencyChain))
+		wg.Go(runGo(version, packageName, constPackageName, ctx, ch, m, false, dependencyChain, overrides))
 	}
 
 	if dev {
 		for packageName, version := range m.NpmDevDependencies {
 			constPackageName := packageName
-			wg.Go(runGo(version, packageName, constPackageName, ctx, ch, m, false, dependencyChain))
+			wg.Go(runGo(version, packageName, constPackageName, ctx, ch, m, false, dependencyChain, overrides))
 		}
 	}
 
 	if optional {
 		for packageName, version := range m.NpmOptionalDependencies {
 			constPackageName := packageName
-			wg.Go(runGo(version, packageName, constPackageName, ctx, ch, m, true, dependencyChain))
+			wg.Go(runGo(version, packageName, constPackageName, ctx, ch, m, true, dependencyChain, overrides))
 		}
 	}
 
@@ -374,44 +447,6 @@ func New() error {
 	return nil
 }
 
-func FindSubMods(root string) []*json.Document[*Mod] {
-	if _, _, _, ok := registry.PackageIdentifierFromPath(root); ok {
-		if mod, err := Load(root); err == nil {
-			return []*json.Document[*Mod]{mod}
-		}
-		return nil
-	}
-
-	ignoreMatcher := ignore.GetIgnoreMatcher(root)
-
-	subMods := []*json.Document[*Mod]{}
-	if err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		if d.IsDir() {
-			gitPath := strings.Split(
-				strings.TrimPrefix(path, root),
-				string(filepath.Separator),
-			)
-			if len(gitPath) != 0 && gitPath[0] == "" {
-				gitPath = gitPath[1:]
-			}
-			if ignoreMatcher.Match(gitPath, true) {
-				return filepath.SkipDir
-			}
-			m, err := Load(path)
-			if err == nil {
-				subMods = append(subMods, m)
-			}
-		}
-		return nil
-	}); err != nil {
-		logger.Errorf("failed to walk %s: %s", root, err)
-	}
-	return subMods
-}
-
 func Install(mod *json.Document[*Mod], pack registry.Package, dev bool) error {
 	if pack.Source != "npm" {
 		return fmt.Errorf("unsupported package source: %s", pack.Source)
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tsukinoko-kun/jmod/meta"
+	"github.com/tsukinoko-kun/jmod/registry"
+)
+
+func TestResolveUnsupportedPlatform(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/")
+		fmt.Fprintf(w, `{"name":%q,"versions":{"1.0.0":{"name":%[1]q,"version":"1.0.0","os":["win32"],"cpu":["x64"],"dist":{"integrity":"sha512-abc"}}}}`, name)
+	}))
+	defer srv.Close()
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	defer func(registryURL string, platform registry.Platform, keepGoing bool) {
+		registry.NpmRegistry = registryURL
+		registry.TargetPlatform = platform
+		registry.KeepGoing = keepGoing
+	}(registry.NpmRegistry, registry.TargetPlatform, registry.KeepGoing)
+	registry.NpmRegistry = srv.URL
+	registry.TargetPlatform = registry.Platform{OS: "linux", CPU: "arm64", Libc: "glibc"}
+	registry.KeepGoing = true
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	meta.CancelCause = cancel
+
+	m := &Mod{
+		NpmDependencies:         map[string]string{"jmod-test-win32-required": "^1.0.0"},
+		NpmOptionalDependencies: map[string]string{"jmod-test-win32-optional": "^1.0.0"},
+	}
+	for dependency := range m.ResolveDependenciesDeep(ctx, false, true, nil, nil) {
+		t.Errorf("expected no dependency to resolve, got %s", dependency.PackageName)
+	}
+
+	failed := map[string]error{}
+	for _, f := range registry.Failures() {
+		failed[f.Package] = f.Err
+	}
+	if err, ok := failed["jmod-test-win32-required"]; !ok || !errors.Is(err, registry.ErrUnsupportedPlatform) {
+		t.Errorf("expected the required dependency to fail with ErrUnsupportedPlatform, got %v", err)
+	}
+	if err, ok := failed["jmod-test-win32-optional"]; ok {
+		t.Errorf("expected the optional dependency to be skipped, got %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Npmrc holds the npm configuration from .npmrc files and npm_config_* environment variables.
+// Keys are lower case with dashes (https-proxy), list keys like ca[] are stored without brackets.
+type Npmrc map[string][]string
+
+// LoadNpmrc reads the user .npmrc, the project .npmrc at root and the npm_config_* environment variables,
+// later sources overriding earlier ones like npm does.
+func LoadNpmrc(root string) Npmrc {
+	rc := Npmrc{}
+	userConfig := os.Getenv("NPM_CONFIG_USERCONFIG")
+	if userConfig == "" {
+		userConfig = os.Getenv("npm_config_userconfig")
+	}
+	if userConfig == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			userConfig = filepath.Join(home, ".npmrc")
+		}
+	}
+	if userConfig != "" {
+		rc.merge(parseNpmrcFile(userConfig))
+	}
+	rc.merge(parseNpmrcFile(filepath.Join(root, ".npmrc")))
+	rc.merge(npmrcFromEnv(os.Environ()))
+	return rc
+}
+
+// Get returns the last value of key.
+func (rc Npmrc) Get(key string) (string, bool) {
+	values := rc[key]
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[len(values)-1], true
+}
+
+// All returns every value of a list key.
+func (rc Npmrc) All(key string) []string {
+	return rc[key]
+}
+
+func (rc Npmrc) merge(other Npmrc) {
+	for key, values := range other {
+		rc[key] = values
+	}
+}
+
+func parseNpmrcFile(path string) Npmrc {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	rc := Npmrc{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = expandNpmrcEnv(unquoteNpmrc(strings.TrimSpace(value)), os.LookupEnv)
+		if list, isList := strings.CutSuffix(key, "[]"); isList {
+			rc[list] = append(rc[list], value)
+		} else {
+			rc[key] = []string{value}
+		}
+	}
+	return rc
+}
+
+// npmrcEnvExpr matches ${NAME} and ${NAME?} with the backslashes escaping it
+var npmrcEnvExpr = regexp.MustCompile(`(\\*)\$\{([^${}?]+)(\?)?\}`)
+
+// expandNpmrcEnv replaces ${NAME} with the environment variable like npm does, a bare $NAME stays as it is.
+// An undefined variable is kept as ${NAME}, or replaced with nothing for ${NAME?}.
+// Every pair of backslashes before it becomes one, an odd backslash escapes the expression.
+func expandNpmrcEnv(value string, lookup func(string) (string, bool)) string {
+	return npmrcEnvExpr.ReplaceAllStringFunc(value, func(match string) string {
+		groups := npmrcEnvExpr.FindStringSubmatch(match)
+		esc, name, optional := groups[1], groups[2], groups[3] != ""
+		if len(esc)%2 == 1 {
+			return match[(len(esc)+1)/2:]
+		}
+		val, ok := lookup(name)
+		if !ok {
+			if optional {
+				val = ""
+			} else {
+				val = "${" + name + "}"
+			}
+		}
+		return esc[len(esc)/2:] + val
+	})
+}
+
+func unquoteNpmrc(value string) string {
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			return unquoted
+		}
+	}
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+func npmrcFromEnv(environ []string) Npmrc {
+	rc := Npmrc{}
+	for _, e := range environ {
+		name, value, _ := strings.Cut(e, "=")
+		key, ok := strings.CutPrefix(strings.ToLower(name), "npm_config_")
+		if !ok || key == "" {
+			continue
+		}
+		rc[strings.ReplaceAll(key, "_", "-")] = []string{value}
+	}
+	return rc
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLoadNpmrc(t *testing.T) {
+	home := t.TempDir()
+	root := t.TempDir()
+	t.Setenv("NPM_CONFIG_USERCONFIG", filepath.Join(home, ".npmrc"))
+	t.Setenv("npm_config_strict_ssl", "false")
+	t.Setenv("PROXY_HOST", "proxy.local")
+
+	user := "proxy=http://user.proxy:8080\nhttps-proxy=http://user.proxy:8443\nstrict-ssl=true\n"
+	project := "# comment\n; comment\nhttps-proxy = \"http://${PROXY_HOST}:3128\"\nca[]=first\nca[]='second'\n_password=pa$$w0rd$PROXY_HOST\n"
+	if err := os.WriteFile(filepath.Join(home, ".npmrc"), []byte(user), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, ".npmrc"), []byte(project), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rc := LoadNpmrc(root)
+	expect := func(key, want string) {
+		t.Helper()
+		if got, _ := rc.Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+	expect("proxy", "http://user.proxy:8080")
+	expect("https-proxy", "http://proxy.local:3128")
+	expect("strict-ssl", "false")
+	expect("_password", "pa$$w0rd$PROXY_HOST")
+	if got := rc.All("ca"); !slices.Equal(got, []string{"first", "second"}) {
+		t.Errorf("ca = %q", got)
+	}
+}
+
+func TestExpandNpmrcEnv(t *testing.T) {
+	env := map[string]string{"TOKEN": "secret", "EMPTY": ""}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+	for value, want := range map[string]string{
+		"${TOKEN}":         "secret",
+		"Bearer ${TOKEN}!": "Bearer secret!",
+		"${EMPTY}x":        "x",
+		"$TOKEN":           "$TOKEN",
+		"pa$$word":         "pa$$word",
+		"${MISSING}":       "${MISSING}",
+		"${MISSING?}":      "",
+		`\${TOKEN}`:        "${TOKEN}",
+		`\\${TOKEN}`:       `\secret`,
+		"$${TOKEN}":        "$secret",
+		"${TOKEN}${TOKEN}": "secretsecret",
+		"${}":              "${}",
+		"${TOKEN":          "${TOKEN",
+	} {
+		if got := expandNpmrcEnv(value, lookup); got != want {
+			t.Errorf("expandNpmrcEnv(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// Overrides forces the version of (transitive) dependencies.
+// It is built from the npm "overrides" and Yarn "resolutions" fields of the project root
+// and scoped to a position in the dependency graph using Descend.
+// A nil *Overrides has no rules.
+type Overrides struct {
+	// root is the directory of the package.json declaring the overrides
+	root   string
+	states []overrideState
+}
+
+type overrideRule struct {
+	// source is the selector as written in package.json, used for reporting
+	source string
+	// path lists the ancestors that have to be passed before the last selector matches
+	path []overrideSelector
+	spec string
+}
+
+type overrideSelector struct {
+	name       string
+	constraint *semver.Constraints
+	// immediate requires this selector to match the direct child of the previous one
+	immediate bool
+}
+
+type overrideState struct {
+	rule *overrideRule
+	// next is the index of the next selector in rule.path to match
+	next int
+}
+
+// Override describes an override applied to a dependency.
+type Override struct {
+	// Selector is the key of the rule as written in package.json
+	Selector string `json:"selector"`
+	// From is the version spec that was requested
+	From string `json:"from"`
+	// To is the version spec that was used instead
+	To string `json:"to"`
+}
+
+func (o Override) String() string {
+	return fmt.Sprintf("%s -> %s (override %q)", o.From, o.To, o.Selector)
+}
+
+// Overrides parses the npm overrides and Yarn resolutions of the module.
+func (m *Mod) Overrides() (*Overrides, error) {
+	var rules []*overrideRule
+	if m.NpmOverrides != nil {
+		r, err := parseNpmOverrides(m, m.NpmOverrides, nil, "")
+		if err != nil {
+			return nil, fmt.Errorf("parse overrides: %w", err)
+		}
+		rules = append(rules, r...)
+	}
+	for key, spec := range m.YarnResolutions {
+		path, err := parseYarnSelector(key)
+		if err != nil {
+			return nil, fmt.Errorf("parse resolutions: %w", err)
+		}
+		rules = append(rules, &overrideRule{source: key, path: path, spec: spec})
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	// JSON object order is lost, keep the result deterministic
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].source < rules[j].source
+	})
+	o := &Overrides{root: filepath.Dir(m.GetFileLocation())}
+	for _, rule := range rules {
+		o.states = append(o.states, overrideState{rule: rule})
+	}
+	return o, nil
+}
+
+// LoadOverrides returns the overrides of the module at root.
+// A missing module results in no overrides.
+func LoadOverrides(root string) (*Overrides, error) {
+	mod, err := Load(root)
+	if err != nil {
+		return nil, nil
+	}
+	return mod.TypedData.Overrides()
+}
+
+func parseNpmOverrides(root *Mod, overrides map[string]any, parent []overrideSelector, parentSource string) ([]*overrideRule, error) {
+	var rules []*overrideRule
+	for key, value := range overrides {
+		source := key
+		if parentSource != "" {
+			source = parentSource + " > " + key
+		}
+
+		var path []overrideSelector
+		if key == "." {
+			if len(parent) == 0 {
+				return nil, fmt.Errorf("%q is only allowed in nested overrides", key)
+			}
+			path = parent
+		} else {
+			selectors, err := parsePnpmSelector(key)
+			if err != nil {
+				return nil, err
+			}
+			path = append(append([]overrideSelector{}, parent...), selectors...)
+		}
+
+		switch v := value.(type) {
+		case string:
+			spec, err := resolveOverrideReference(root, v)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", source, err)
+			}
+			rules = append(rules, &overrideRule{source: source, path: path, spec: spec})
+		case map[string]any:
+			if key == "." {
+				return nil, fmt.Errorf("%s: expected a version", source)
+			}
+			nested, err := parseNpmOverrides(root, v, path, source)
+			if err != nil {
+				return nil, err
+			}
+			rules = append(rules, nested...)
+		default:
+			return nil, fmt.Errorf("%s: unexpected value %v", source, value)
+		}
+	}
+	return rules, nil
+}
+
+// resolveOverrideReference resolves npm's "$name" syntax to the version the root module depends on.
+func resolveOverrideReference(root *Mod, spec string) (string, error) {
+	name, ok := strings.CutPrefix(spec, "$")
+	if !ok {
+		return spec, nil
+	}
+	for _, deps := range []map[string]string{root.NpmDependencies, root.NpmDevDependencies, root.NpmOptionalDependencies} {
+		if version, ok := deps[name]; ok {
+			return version, nil
+		}
+	}
+	return "", fmt.Errorf("%s references a package that is not a direct dependency", spec)
+}
+
+// parsePnpmSelector parses "foo", "foo@1.x" and "foo>bar" where bar has to be a direct dependency of foo.
+func parsePnpmSelector(key string) ([]overrideSelector, error) {
+	var path []overrideSelector
+	for i, part := range strings.Split(key, ">") {
+		selector, err := parseOverrideSelector(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", key, err)
+		}
+		selector.immediate = i > 0
+		path = append(path, selector)
+	}
+	return path, nil
+}
+
+// parseYarnSelector parses Yarn resolution keys like "bar", "**/bar", "foo/bar" and "foo/**/bar".
+func parseYarnSelector(key string) ([]overrideSelector, error) {
+	parts := strings.Split(key, "/")
+	var path []overrideSelector
+	immediate := false
+	for i := 0; i < len(parts); i++ {
+		part := parts[i]
+		if part == "**" {
+			immediate = false
+			continue
+		}
+		if strings.HasPrefix(part, "@") {
+			if i+1 >= len(parts) {
+				return nil, fmt.Errorf("%q: incomplete scoped package name", key)
+			}
+			i++
+			part += "/" + parts[i]
+		}
+		selector, err := parseOverrideSelector(part)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", key, err)
+		}
+		selector.immediate = immediate && len(path) > 0
+		path = append(path, selector)
+		immediate = true
+	}
+	if len(path) == 0 {
+		return nil, fmt.Errorf("%q: no package name", key)
+	}
+	return path, nil
+}
+
+func parseOverrideSelector(s string) (overrideSelector, error) {
+	if s == "" {
+		return overrideSelector{}, fmt.Errorf("empty package name")
+	}
+	// scoped packages start with @, the version separator is the last @ after that
+	if at := strings.LastIndex(s, "@"); at > 0 {
+		c, err := semver.NewConstraint(s[at+1:])
+		if err != nil {
+			return overrideSelector{}, fmt.Errorf("invalid version range %q: %w", s[at+1:], err)
+		}
+		return overrideSelector{name: s[:at], constraint: c}, nil
+	}
+	return overrideSelector{name: s}, nil
+}
+
+// matches reports whether a dependency name with the given version is selected.
+// Selectors with a version range never match a version range, see NeedsVersion.
+func (s overrideSelector) matches(name string, version string) bool {
+	if s.name != name {
+		return false
+	}
+	if s.constraint == nil {
+		return true
+	}
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return false
+	}
+	return s.constraint.Check(v)
+}
+
+// Root returns the directory of the package.json declaring the overrides.
+func (o *Overrides) Root() string {
+	if o == nil {
+		return ""
+	}
+	return o.root
+}
+
+// NeedsVersion reports whether a rule in the current scope selects name by a version range.
+// Such rules are matched against the version a dependency resolves to,
+// so Lookup has to be called with that version instead of the requested range.
+func (o *Overrides) NeedsVersion(name string) bool {
+	if o == nil {
+		return false
+	}
+	for _, state := range o.states {
+		selector := state.rule.path[state.next]
+		if state.next == len(state.rule.path)-1 && selector.name == name && selector.constraint != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Fingerprint identifies the rules in the current scope, it is empty if there are none.
+// Packages whose dependencies are resolved with different rules must not share their node_modules.
+func (o *Overrides) Fingerprint() string {
+	if o == nil || len(o.states) == 0 {
+		return ""
+	}
+	keys := make([]string, len(o.states))
+	for i, state := range o.states {
+		keys[i] = fmt.Sprintf("%s\x00%d", state.rule.source, state.next)
+	}
+	sort.Strings(keys)
+	sum := sha256.Sum256([]byte(strings.Join(keys, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Lookup returns the override for a dependency requested with the given version spec in the current scope.
+// The most specific matching rule wins.
+func (o *Overrides) Lookup(name string, spec string) (Override, bool) {
+	if o == nil {
+		return Override{}, false
+	}
+	var best *overrideRule
+	for _, state := range o.states {
+		if state.next != len(state.rule.path)-1 {
+			continue
+		}
+		if !state.rule.path[state.next].matches(name, spec) {
+			continue
+		}
+		if best == nil || len(state.rule.path) > len(best.path) {
+			best = state.rule
+		}
+	}
+	if best == nil || best.spec == spec {
+		return Override{}, false
+	}
+	return Override{Selector: best.source, From: spec, To: best.spec}, true
+}
+
+// Descend returns the overrides that apply to the dependencies of the given package.
+func (o *Overrides) Descend(name string, version string) *Overrides {
+	if o == nil {
+		return nil
+	}
+	child := &Overrides{root: o.root}
+	seen := make(map[overrideState]struct{}, len(o.states))
+	keep := func(state overrideState) {
+		if _, ok := seen[state]; !ok {
+			seen[state] = struct{}{}
+			child.states = append(child.states, state)
+		}
+	}
+	for _, state := range o.states {
+		selector := state.rule.path[state.next]
+		if state.next < len(state.rule.path)-1 && selector.matches(name, version) {
+			// an ancestor selector matched, the next one has to match below this package
+			keep(overrideState{rule: state.rule, next: state.next + 1})
+		}
+		// immediate selectors only apply directly below the previous match
+		if !selector.immediate {
+			keep(state)
+		}
+	}
+	return child
+}
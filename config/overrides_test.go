@@ -0,0 +1,99 @@
+package config
+
+import "testing"
+
+func TestOverrides(t *testing.T) {
+	m := &Mod{
+		NpmDependencies: map[string]string{"react": "^18.3.1"},
+		NpmOverrides: map[string]any{
+			"semver": "7.5.4",
+			"foo": map[string]any{
+				".":   "2.0.0",
+				"bar": "1.0.1",
+			},
+			"baz>qux":  "3.0.0",
+			"react-is": "$react",
+		},
+		YarnResolutions: map[string]string{
+			"@scope/a/**/b": "4.0.0",
+		},
+	}
+	overrides, err := m.Overrides()
+	if err != nil {
+		t.Fatalf("Overrides: %v", err)
+	}
+
+	expect := func(o *Overrides, name, spec, want string) {
+		t.Helper()
+		got, ok := o.Lookup(name, spec)
+		if want == "" {
+			if ok {
+				t.Errorf("%s@%s: unexpected override to %s", name, spec, got.To)
+			}
+			return
+		}
+		if !ok || got.To != want {
+			t.Errorf("%s@%s: got %q (ok=%v), want %q", name, spec, got.To, ok, want)
+		}
+	}
+
+	// global rules
+	expect(overrides, "semver", "^6.0.0", "7.5.4")
+	expect(overrides, "foo", "^1.0.0", "2.0.0")
+	expect(overrides, "react-is", "^16.0.0", "^18.3.1")
+	expect(overrides, "bar", "^1.0.0", "")
+
+	// nested npm overrides apply anywhere below foo
+	underFoo := overrides.Descend("foo", "2.0.0").Descend("other", "1.0.0")
+	expect(underFoo, "bar", "^1.0.0", "1.0.1")
+	expect(underFoo, "semver", "^6.0.0", "7.5.4")
+
+	// "baz>qux" only applies to direct dependencies of baz
+	underBaz := overrides.Descend("baz", "1.0.0")
+	expect(underBaz, "qux", "^1.0.0", "3.0.0")
+	expect(underBaz.Descend("other", "1.0.0"), "qux", "^1.0.0", "")
+
+	// yarn "**" allows any depth
+	underA := overrides.Descend("@scope/a", "1.0.0").Descend("x", "1.0.0").Descend("y", "1.0.0")
+	expect(underA, "b", "^1.0.0", "4.0.0")
+	expect(overrides, "b", "^1.0.0", "")
+}
+
+func TestOverridesVersionSelector(t *testing.T) {
+	m := &Mod{NpmOverrides: map[string]any{"foo@1.x": "1.9.9"}}
+	overrides, err := m.Overrides()
+	if err != nil {
+		t.Fatalf("Overrides: %v", err)
+	}
+	if !overrides.NeedsVersion("foo") || overrides.NeedsVersion("bar") {
+		t.Errorf("expected only foo to need its resolved version")
+	}
+	if o, ok := overrides.Lookup("foo", "1.2.0"); !ok || o.To != "1.9.9" {
+		t.Errorf("expected foo@1.2.0 to be overridden, got %v %v", o, ok)
+	}
+	for _, version := range []string{"2.0.0", "^1.0.0"} {
+		if _, ok := overrides.Lookup("foo", version); ok {
+			t.Errorf("foo@%s must not be overridden", version)
+		}
+	}
+}
+
+func TestOverridesFingerprint(t *testing.T) {
+	m := &Mod{NpmOverrides: map[string]any{"baz>qux": "3.0.0"}}
+	overrides, err := m.Overrides()
+	if err != nil {
+		t.Fatalf("Overrides: %v", err)
+	}
+	var none *Overrides
+	if none.Fingerprint() != "" {
+		t.Errorf("expected no fingerprint without rules")
+	}
+	// the rule applies to any subtree that contains baz
+	if overrides.Descend("other", "1.0.0").Fingerprint() != overrides.Fingerprint() {
+		t.Errorf("expected scopes with the same rules to have the same fingerprint")
+	}
+	underBaz := overrides.Descend("baz", "1.0.0").Fingerprint()
+	if underBaz == "" || underBaz == overrides.Fingerprint() {
+		t.Errorf("expected scopes with different rules to have different fingerprints")
+	}
+}
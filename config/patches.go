@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// Patches maps packages to the patch files configured in the patchedDependencies field of the project root.
+// Keys are either name@version or just the name to patch every version.
+// A nil *Patches has no entries.
+type Patches struct {
+	// root is the directory patch paths are relative to
+	root    string
+	entries map[string]string
+}
+
+// Patches returns the patchedDependencies of the module.
+func (m *Mod) Patches() *Patches {
+	if len(m.PatchedDependencies) == 0 {
+		return nil
+	}
+	return &Patches{
+		root:    filepath.Dir(m.GetFileLocation()),
+		entries: m.PatchedDependencies,
+	}
+}
+
+// LoadPatches returns the patchedDependencies of the module at root.
+// A missing module results in no patches.
+func LoadPatches(root string) *Patches {
+	mod, err := Load(root)
+	if err != nil {
+		return nil
+	}
+	return mod.TypedData.Patches()
+}
+
+// Root returns the directory of the package.json declaring the patches.
+func (p *Patches) Root() string {
+	if p == nil {
+		return ""
+	}
+	return p.root
+}
+
+// Lookup returns the absolute path of the patch file for a package.
+func (p *Patches) Lookup(name string, version string) (string, bool) {
+	if p == nil {
+		return "", false
+	}
+	patchFile, ok := p.entries[fmt.Sprintf("%s@%s", name, version)]
+	if !ok {
+		patchFile, ok = p.entries[name]
+	}
+	if !ok {
+		return "", false
+	}
+	return filepath.Join(p.root, filepath.FromSlash(patchFile)), true
+}
@@ -0,0 +1,80 @@
+package config
+
+// Settings holds the jmod specific options from the "jmod" field of a package.json.
+// Only the settings of the project root are applied.
+type Settings struct {
+	// EngineStrict makes the install fail if a dependency's engines field is not satisfied.
+	EngineStrict bool `json:"engineStrict,omitempty"`
+	// PreferCompatibleEngines resolves version ranges to the highest version whose engines field is satisfied.
+	PreferCompatibleEngines bool `json:"preferCompatibleEngines,omitempty"`
+	// Registry is the base URL of the npm registry.
+	Registry string `json:"registry,omitempty"`
+	// SignatureKeys is the URL of the registry signing keys, defaults to <registry>/-/npm/v1/keys.
+	SignatureKeys string `json:"signatureKeys,omitempty"`
+	// Signatures is "warn" (default), "strict" or "off".
+	Signatures string `json:"signatures,omitempty"`
+	// MaxSockets limits the concurrent requests per registry host, defaults to 16.
+	MaxSockets int `json:"maxSockets,omitempty"`
+	// FetchRetries is how often failed registry and tarball requests are retried, defaults to 3.
+	FetchRetries *int `json:"fetchRetries,omitempty"`
+	// FetchRetryMinTimeout is the delay before the first retry in milliseconds, defaults to 1000.
+	FetchRetryMinTimeout int `json:"fetchRetryMinTimeout,omitempty"`
+	// FetchRetryMaxTimeout caps the delay between retries in milliseconds, defaults to 30000.
+	FetchRetryMaxTimeout int `json:"fetchRetryMaxTimeout,omitempty"`
+	// Audit configures `jmod audit`.
+	Audit *AuditSettings `json:"audit,omitempty"`
+	// Sandbox configures the sandbox dependency lifecycle scripts run in.
+	Sandbox *SandboxSettings `json:"sandbox,omitempty"`
+	// ScriptConcurrency limits how many packages run lifecycle scripts at once, defaults to the number of CPUs.
+	ScriptConcurrency int `json:"scriptConcurrency,omitempty"`
+	// Tidy configures `jmod tidy`.
+	Tidy *TidySettings `json:"tidy,omitempty"`
+}
+
+type TidySettings struct {
+	// Keep lists dependencies that are never removed as unused, by name or glob like @types/*.
+	Keep []string `json:"keep,omitempty"`
+	// DevFiles are gitignore style patterns of files that are only used in development,
+	// like tests, stories and tool configs. Packages only they import are devDependencies.
+	// Replaces the default patterns.
+	DevFiles []string `json:"devFiles,omitempty"`
+	// Ignore lists imported packages that are never added, by name or glob,
+	// for modules provided by a bundler or framework.
+	Ignore []string `json:"ignore,omitempty"`
+}
+
+type AuditSettings struct {
+	// Ignore lists advisories by id, URL or GHSA id that are not reported.
+	Ignore []string `json:"ignore,omitempty"`
+	// Level is the minimum severity that makes the audit fail, defaults to "low".
+	Level string `json:"level,omitempty"`
+}
+
+type SandboxSettings struct {
+	// Enabled runs dependency lifecycle scripts without network access,
+	// with write access only to the package directory and the temp dir and without secrets in the environment.
+	Enabled bool `json:"enabled,omitempty"`
+	// Packages holds per-package exceptions keyed by package name.
+	Packages map[string]SandboxPackageSettings `json:"packages,omitempty"`
+}
+
+type SandboxPackageSettings struct {
+	// Disabled runs the scripts of the package without sandbox.
+	Disabled bool `json:"disabled,omitempty"`
+	// Network allows network access.
+	Network bool `json:"network,omitempty"`
+	// Env lists sensitive environment variables that are passed to the scripts anyway.
+	Env []string `json:"env,omitempty"`
+	// Write lists additional writable paths, absolute or relative to the home directory with ~/.
+	Write []string `json:"write,omitempty"`
+}
+
+// LoadSettings returns the settings of the module at root.
+// Missing or unreadable modules result in the default settings.
+func LoadSettings(root string) Settings {
+	mod, err := Load(root)
+	if err != nil || mod.TypedData.Jmod == nil {
+		return Settings{}
+	}
+	return *mod.TypedData.Jmod
+}
@@ -0,0 +1,33 @@
+package config
+
+// TrustedDependencies are the packages whose lifecycle scripts are allowed to run,
+// configured in the trustedDependencies field of the project root.
+// A nil TrustedDependencies trusts no dependency.
+type TrustedDependencies map[string]struct{}
+
+// TrustedDependencies returns the trustedDependencies of the module.
+func (m *Mod) TrustedDependencies() TrustedDependencies {
+	if len(m.NpmTrustedDependencies) == 0 {
+		return nil
+	}
+	trusted := make(TrustedDependencies, len(m.NpmTrustedDependencies))
+	for _, name := range m.NpmTrustedDependencies {
+		trusted[name] = struct{}{}
+	}
+	return trusted
+}
+
+// LoadTrustedDependencies returns the trustedDependencies of the module at root.
+// A missing module results in no trusted dependencies.
+func LoadTrustedDependencies(root string) TrustedDependencies {
+	mod, err := Load(root)
+	if err != nil {
+		return nil
+	}
+	return mod.TypedData.TrustedDependencies()
+}
+
+func (t TrustedDependencies) Has(name string) bool {
+	_, ok := t[name]
+	return ok
+}
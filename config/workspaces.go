@@ -0,0 +1,235 @@
+package config
+
+import (
+	_json "encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/tsukinoko-kun/jmod/ignore"
+	"github.com/tsukinoko-kun/jmod/logger"
+	"github.com/tsukinoko-kun/jmod/registry"
+	json "github.com/tsukinoko-kun/jsonedit"
+)
+
+// FindSubMods returns the module at root and the modules of its workspace, sorted by path.
+// If the root declares workspaces only the matching directories are modules,
+// otherwise every directory with a package.json that is not ignored is one.
+func FindSubMods(root string) []*json.Document[*Mod] {
+	if _, _, _, ok := registry.PackageIdentifierFromPath(root); ok {
+		if mod, err := Load(root); err == nil {
+			return []*json.Document[*Mod]{mod}
+		}
+		return nil
+	}
+
+	ignoreMatcher := ignore.GetIgnoreMatcher(root)
+
+	var mu sync.Mutex
+	subMods := []*json.Document[*Mod]{}
+	found := map[string]bool{}
+	add := func(dir string, entries []os.DirEntry) {
+		if !hasPackageFile(entries) {
+			return
+		}
+		m, err := Load(dir)
+		if err != nil {
+			logger.Printf("failed to load %s: %s", dir, err)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if !found[dir] {
+			found[dir] = true
+			subMods = append(subMods, m)
+		}
+	}
+
+	patterns, ok := WorkspacePatterns(root)
+	if !ok {
+		walkDirs(root, root, func(rel []string) bool {
+			return ignoreMatcher.Match(rel, true)
+		}, func(dir string, rel []string, entries []os.DirEntry) {
+			add(dir, entries)
+		})
+	} else {
+		if entries, err := os.ReadDir(root); err == nil {
+			add(root, entries)
+		}
+		var include, exclude []string
+		for _, pattern := range patterns {
+			pattern = path.Clean(strings.TrimPrefix(filepath.ToSlash(pattern), "./"))
+			if negated, ok := strings.CutPrefix(pattern, "!"); ok {
+				exclude = append(exclude, path.Clean(strings.TrimPrefix(negated, "./")))
+			} else {
+				include = append(include, pattern)
+			}
+		}
+		for _, pattern := range include {
+			segments := strings.Split(pattern, "/")
+			walkDirs(root, filepath.Join(root, filepath.FromSlash(staticPrefix(segments))), func(rel []string) bool {
+				return ignoreMatcher.Match(rel, true) || !matchWorkspacePrefix(segments, rel)
+			}, func(dir string, rel []string, entries []os.DirEntry) {
+				if !matchWorkspace(segments, rel) {
+					return
+				}
+				for _, excluded := range exclude {
+					if matchWorkspace(strings.Split(excluded, "/"), rel) {
+						return
+					}
+				}
+				add(dir, entries)
+			})
+		}
+	}
+
+	slices.SortFunc(subMods, func(a, b *json.Document[*Mod]) int {
+		return strings.Compare(filepath.Dir(a.TypedData.fileLocation), filepath.Dir(b.TypedData.fileLocation))
+	})
+	return subMods
+}
+
+// WorkspacePatterns returns the workspaces globs of the package.json at root,
+// declared as array or as packages field like yarn does.
+func WorkspacePatterns(root string) ([]string, bool) {
+	file, err := GetPackageFilePath(root)
+	if err != nil {
+		return nil, false
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, false
+	}
+	var pj struct {
+		Workspaces _json.RawMessage `json:"workspaces"`
+	}
+	if err := _json.Unmarshal(data, &pj); err != nil || len(pj.Workspaces) == 0 || string(pj.Workspaces) == "null" {
+		return nil, false
+	}
+	var patterns []string
+	if err := _json.Unmarshal(pj.Workspaces, &patterns); err == nil {
+		return patterns, true
+	}
+	var yarn struct {
+		Packages []string `json:"packages"`
+	}
+	if err := _json.Unmarshal(pj.Workspaces, &yarn); err == nil {
+		return yarn.Packages, true
+	}
+	logger.Warnf("%s: workspaces must be a list of globs", file)
+	return nil, false
+}
+
+func hasPackageFile(entries []os.DirEntry) bool {
+	return slices.ContainsFunc(entries, func(e os.DirEntry) bool {
+		return !e.IsDir() && slices.Contains(packageFileName, e.Name())
+	})
+}
+
+// staticPrefix returns the leading segments of a workspace pattern without glob characters.
+func staticPrefix(segments []string) string {
+	var prefix []string
+	for _, segment := range segments {
+		if strings.ContainsAny(segment, "*?[") {
+			break
+		}
+		prefix = append(prefix, segment)
+	}
+	return path.Join(prefix...)
+}
+
+// matchWorkspace reports whether the directory rel matches the workspace pattern,
+// where ** matches any number of directories.
+func matchWorkspace(segments []string, rel []string) bool {
+	if len(segments) == 0 {
+		return len(rel) == 0
+	}
+	if segments[0] == "**" {
+		for i := 0; i <= len(rel); i++ {
+			if matchWorkspace(segments[1:], rel[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(rel) == 0 {
+		return false
+	}
+	ok, _ := path.Match(segments[0], rel[0])
+	return ok && matchWorkspace(segments[1:], rel[1:])
+}
+
+// matchWorkspacePrefix reports whether directories below rel can match the workspace pattern.
+func matchWorkspacePrefix(segments []string, rel []string) bool {
+	for i, name := range rel {
+		if i >= len(segments) {
+			return false
+		}
+		if segments[i] == "**" {
+			return true
+		}
+		if ok, _ := path.Match(segments[i], name); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// walkDirs visits dir and its subdirectories in parallel, with the path relative to root.
+// Directories for which skip returns true are neither visited nor read.
+func walkDirs(root string, dir string, skip func(rel []string) bool, visit func(dir string, rel []string, entries []os.DirEntry)) {
+	rel := splitRel(root, dir)
+	if len(rel) != 0 && skip(rel) {
+		return
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return
+	}
+
+	sem := make(chan struct{}, runtime.NumCPU()*2)
+	var wg sync.WaitGroup
+	var walk func(dir string, rel []string)
+	walk = func(dir string, rel []string) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			logger.Errorf("failed to read %s: %s", dir, err)
+			return
+		}
+		visit(dir, rel, entries)
+		for _, entry := range entries {
+			// symlinks are not followed
+			if !entry.IsDir() {
+				continue
+			}
+			subDir := filepath.Join(dir, entry.Name())
+			subRel := append(slices.Clip(rel), entry.Name())
+			if skip(subRel) {
+				continue
+			}
+			select {
+			case sem <- struct{}{}:
+				wg.Go(func() {
+					defer func() { <-sem }()
+					walk(subDir, subRel)
+				})
+			default:
+				// all workers are busy, walking inline keeps the number of goroutines bounded
+				walk(subDir, subRel)
+			}
+		}
+	}
+	walk(dir, rel)
+	wg.Wait()
+}
+
+func splitRel(root string, dir string) []string {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == "." {
+		return nil
+	}
+	return strings.Split(filepath.ToSlash(rel), "/")
+}
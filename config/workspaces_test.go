@@ -0,0 +1,40 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/tsukinoko-kun/jmod/utils"
+)
+
+func TestFindSubModsWorkspaces(t *testing.T) {
+	root := t.TempDir()
+	for file, content := range map[string]string{
+		"package.json":                         `{"workspaces": ["packages/*", "tools/**", "!packages/legacy"]}`,
+		"packages/a/package.json":              `{}`,
+		"packages/legacy/package.json":         `{}`,
+		"packages/a/test/fixture/package.json": `{}`,
+		"tools/build/deep/package.json":        `{}`,
+		"tools/node_modules/x/package.json":    `{}`,
+		"examples/demo/package.json":           `{}`,
+	} {
+		path := filepath.Join(root, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []string
+	for _, mod := range FindSubMods(root) {
+		got = append(got, filepath.ToSlash(utils.Must(filepath.Rel(root, filepath.Dir(mod.TypedData.GetFileLocation())))))
+	}
+	want := []string{".", "packages/a", "tools/build/deep"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -8,6 +8,8 @@ require (
 	github.com/tree-sitter/go-tree-sitter v0.25.0
 	github.com/tree-sitter/tree-sitter-typescript v0.23.2
 	github.com/tsukinoko-kun/jsonedit v0.1.2
+	golang.org/x/net v0.39.0
+	golang.org/x/sys v0.37.0
 )
 
 require (
@@ -41,8 +43,6 @@ require (
 	github.com/tsukinoko-kun/disize v0.1.0 // indirect
 	github.com/ulikunitz/xz v0.5.15 // indirect
 	github.com/xo/terminfo v0.0.0-20220910002029-abceb7e1c41e // indirect
-	golang.org/x/net v0.39.0 // indirect
-	golang.org/x/sys v0.37.0 // indirect
 	golang.org/x/text v0.30.0 // indirect
 	gopkg.in/warnings.v0 v0.1.2 // indirect
 )
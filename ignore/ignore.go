@@ -1,13 +1,14 @@
 package ignore
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
-	"io"
-	"io/fs"
 	"os"
 	"path/filepath"
 	"slices"
 	"strings"
+	"sync"
 
 	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
 )
@@ -43,25 +44,40 @@ var ignorePaths = []string{
 	".vars",
 }
 
-var ignoreMatchers = map[string]gitignore.Matcher{}
+// ignoreFiles are read in every directory, later files take precedence.
+// .jmodignore excludes paths from jmod only, like fixtures that are checked in.
+var ignoreFiles = []string{".gitignore", ".jmodignore"}
 
+var (
+	ignoreMatchersMu sync.Mutex
+	ignoreMatchers   = map[string]*lazyMatcher{}
+)
+
+// GetIgnoreMatcher returns the matcher of the paths below path that jmod skips.
+// It is created once per path and safe for concurrent use.
+// The ignore files of a directory are read the first time a path below it is matched,
+// so callers that only walk part of the tree never read the rest.
 func GetIgnoreMatcher(path string) gitignore.Matcher {
-	matcher, ok := ignoreMatchers[path]
-	if ok {
+	ignoreMatchersMu.Lock()
+	defer ignoreMatchersMu.Unlock()
+	if matcher, ok := ignoreMatchers[path]; ok {
 		return matcher
 	}
-
-	matcher, err := getIgnoreMatcher(path)
+	base := defaultPatterns()
+	exclude, err := readPatterns(filepath.Join(path, ".git", "info", "exclude"), nil)
 	if err != nil {
 		_, _ = fmt.Fprintln(os.Stderr, err)
-		return nil
+	}
+	matcher := &lazyMatcher{
+		root: path,
+		base: append(base, exclude...),
+		dirs: map[string]*dirPatterns{},
 	}
 	ignoreMatchers[path] = matcher
-
 	return matcher
 }
 
-func getIgnoreMatcher(path string) (gitignore.Matcher, error) {
+func defaultPatterns() []gitignore.Pattern {
 	var patterns []gitignore.Pattern
 	for _, dir := range ignoreDirs {
 		patterns = append(patterns, gitignore.ParsePattern(dir+"/", nil))
@@ -69,39 +85,95 @@ func getIgnoreMatcher(path string) (gitignore.Matcher, error) {
 	for _, path := range ignorePaths {
 		patterns = append(patterns, gitignore.ParsePattern(path, nil))
 	}
+	return patterns
+}
 
-	if err := filepath.WalkDir(path, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
+// lazyMatcher matches paths relative to root against the ignore files of their parent directories.
+// The patterns of an ignore file only apply to its directory, and patterns of nested files
+// follow those of their parents so they can override them, like in git.
+type lazyMatcher struct {
+	root string
+	// base are the default patterns and those of .git/info/exclude
+	base []gitignore.Pattern
+
+	mu   sync.Mutex
+	dirs map[string]*dirPatterns
+}
+
+// dirPatterns are the patterns that apply inside a directory:
+// those of its parents followed by its own ignore files.
+type dirPatterns struct {
+	once     sync.Once
+	patterns []gitignore.Pattern
+	matcher  gitignore.Matcher
+}
+
+// Match reports whether path or one of its parent directories is ignored.
+// The ignore files inside an ignored directory are never read.
+func (m *lazyMatcher) Match(path []string, isDir bool) bool {
+	for i := 1; i < len(path); i++ {
+		if m.patternsOf(path[:i-1]).matcher.Match(path[:i], true) {
+			return true
 		}
-		if d.IsDir() {
-			if slices.Contains(ignoreDirs, d.Name()) {
-				return filepath.SkipDir
-			}
-			return nil
+	}
+	return m.patternsOf(path[:max(len(path)-1, 0)]).matcher.Match(path, isDir)
+}
+
+// patternsOf returns the patterns of the directory rel, built once from those of its parent.
+func (m *lazyMatcher) patternsOf(rel []string) *dirPatterns {
+	key := strings.Join(rel, "/")
+	m.mu.Lock()
+	dir, ok := m.dirs[key]
+	if !ok {
+		dir = &dirPatterns{}
+		m.dirs[key] = dir
+	}
+	m.mu.Unlock()
+
+	dir.once.Do(func() {
+		parent := m.base
+		if len(rel) > 0 {
+			parent = m.patternsOf(rel[:len(rel)-1]).patterns
 		}
-		if d.Name() == ".gitignore" {
-			f, err := os.Open(path)
-			if err != nil {
-				return err
-			}
-			defer f.Close()
-			ignoreBytes, err := io.ReadAll(f)
+		dir.patterns = slices.Clip(parent)
+		for _, name := range ignoreFiles {
+			patterns, err := readPatterns(filepath.Join(m.root, filepath.FromSlash(key), name), rel)
 			if err != nil {
-				return err
-			}
-			ignoreStr := string(ignoreBytes)
-			for line := range strings.Lines(ignoreStr) {
-				if line == "" || strings.HasPrefix(line, "#") {
-					continue
-				}
-				patterns = append(patterns, gitignore.ParsePattern(line, nil))
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				continue
 			}
+			dir.patterns = append(dir.patterns, patterns...)
+		}
+		dir.matcher = gitignore.NewMatcher(dir.patterns)
+	})
+	return dir
+}
+
+// readPatterns parses the ignore file at file, whose patterns are relative to domain.
+// A missing file has no patterns.
+func readPatterns(file string, domain []string) ([]gitignore.Pattern, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
 		}
-		return nil
-	}); err != nil {
 		return nil, err
 	}
-
-	return gitignore.NewMatcher(patterns), nil
+	var patterns []gitignore.Pattern
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		// trailing spaces are ignored unless escaped with a backslash
+		if trimmed := strings.TrimRight(line, " \t"); !strings.HasSuffix(trimmed, `\`) {
+			line = trimmed
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, gitignore.ParsePattern(line, slices.Clone(domain)))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", file, err)
+	}
+	return patterns, nil
 }
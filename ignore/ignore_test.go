@@ -0,0 +1,121 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIgnoreMatcher(t *testing.T) {
+	root := t.TempDir()
+	for file, content := range map[string]string{
+		".gitignore":                  "dist\r\n*.log\n!keep.log\n",
+		".git/info/exclude":           "scratch/\n",
+		"packages/foo/.gitignore":     "generated\n",
+		"packages/foo/.jmodignore":    "fixtures/\n",
+		"packages/bar/generated/a.ts": "",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	matcher := GetIgnoreMatcher(root)
+	for path, want := range map[string]bool{
+		"dist":                      true,
+		"packages/foo/dist":         true,
+		"error.log":                 true,
+		"keep.log":                  false,
+		"scratch":                   true,
+		"packages/foo/generated":    true,
+		"packages/bar/generated":    false,
+		"packages/foo/fixtures":     true,
+		"packages/bar/fixtures":     false,
+		"node_modules":              true,
+		"packages/foo/node_modules": true,
+		"packages/foo/src":          false,
+	} {
+		isDir := !strings.Contains(path, ".")
+		if got := matcher.Match(strings.Split(path, "/"), isDir); got != want {
+			t.Errorf("Match(%s) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestIgnoreMatcherLazy(t *testing.T) {
+	root := t.TempDir()
+	for file, content := range map[string]string{
+		".gitignore":       "build/\n",
+		"build/.gitignore": "!*\n",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	matcher := GetIgnoreMatcher(root)
+	// written after the matcher was created, read when the directory is reached
+	if err := os.MkdirAll(filepath.Join(root, "packages", "late"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "packages", "late", ".jmodignore"), []byte("tmp/\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if !matcher.Match([]string{"packages", "late", "tmp"}, true) {
+		t.Error("expected the ignore file of a directory to be read when it is reached")
+	}
+	if !matcher.Match([]string{"build", "out.js"}, false) {
+		t.Error("expected files in an ignored directory to stay ignored")
+	}
+	lazy := matcher.(*lazyMatcher)
+	if _, ok := lazy.dirs["build"]; ok {
+		t.Error("expected the ignore files of an ignored directory not to be read")
+	}
+}
+
+func TestIgnoreMatcherDirPatterns(t *testing.T) {
+	root := t.TempDir()
+	for file, content := range map[string]string{
+		".gitignore":              "*.log\n",
+		"packages/.jmodignore":    "tmp/\n",
+		"packages/app/.gitignore": "!keep.log\n",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lazy := GetIgnoreMatcher(root).(*lazyMatcher)
+	if !lazy.Match([]string{"packages", "app", "debug.log"}, false) {
+		t.Error("expected the patterns of parent directories to apply")
+	}
+	if lazy.Match([]string{"packages", "app", "keep.log"}, false) {
+		t.Error("expected nested patterns to override those of their parents")
+	}
+	if !lazy.Match([]string{"packages", "app", "tmp"}, true) {
+		t.Error("expected the patterns of a nested directory to apply below it")
+	}
+
+	app := lazy.patternsOf([]string{"packages", "app"})
+	if lazy.patternsOf([]string{"packages", "app"}) != app {
+		t.Error("expected the patterns of a directory to be built once")
+	}
+	parent := lazy.patternsOf([]string{"packages"}).patterns
+	if len(app.patterns) != len(parent)+1 {
+		t.Errorf("expected packages/app to extend the %d patterns of its parent with its own, got %d", len(parent), len(app.patterns))
+	}
+}
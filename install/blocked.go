@@ -0,0 +1,126 @@
+package install
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/tsukinoko-kun/jmod/config"
+)
+
+// installScripts are the lifecycle scripts run for dependencies during install
+var installScripts = []string{"preinstall", "install", "postinstall"}
+
+var (
+	blockedScriptsMu sync.Mutex
+	blockedScripts   = make(map[string]struct{})
+)
+
+// allowScripts reports whether the lifecycle scripts of the dependency at packageJsonPath may run.
+// Untrusted dependencies that have install scripts are remembered for BlockedScripts.
+func allowScripts(packageJsonPath string, trusted config.TrustedDependencies) bool {
+	pj, err := config.GetPackageJsonForLifecycle(packageJsonPath)
+	if err != nil || pj.Name == nil {
+		return false
+	}
+	if trusted.Has(*pj.Name) {
+		return true
+	}
+	if hasInstallScripts(packageJsonPath, pj.Scripts) {
+		blockedScriptsMu.Lock()
+		blockedScripts[*pj.Name] = struct{}{}
+		blockedScriptsMu.Unlock()
+	}
+	return false
+}
+
+func hasInstallScripts(packageJsonPath string, scripts *map[string]string) bool {
+	if scripts != nil {
+		for _, script := range installScripts {
+			if _, ok := (*scripts)[script]; ok {
+				return true
+			}
+		}
+	}
+	return needsNodeGyp(packageJsonPath)
+}
+
+// BlockedScripts returns the names of the dependencies whose lifecycle scripts were skipped
+// because they are not listed in trustedDependencies.
+func BlockedScripts() []string {
+	blockedScriptsMu.Lock()
+	defer blockedScriptsMu.Unlock()
+	names := make([]string, 0, len(blockedScripts))
+	for name := range blockedScripts {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func blockedScriptsFile(root string) string {
+	return filepath.Join(root, "node_modules", ".jmod", "blocked-scripts.json")
+}
+
+// WriteBlockedScripts stores the BlockedScripts of this install for `jmod approve-builds`.
+func WriteBlockedScripts(root string) error {
+	names := BlockedScripts()
+	file := blockedScriptsFile(root)
+	if len(names) == 0 {
+		if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(names, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0o644)
+}
+
+// ReadBlockedScripts returns the blocked dependencies recorded by the last install at root.
+func ReadBlockedScripts(root string) ([]string, error) {
+	data, err := os.ReadFile(blockedScriptsFile(root))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// ApproveBuilds adds names to the trustedDependencies of the module at root.
+// It returns the names that were not trusted before.
+func ApproveBuilds(root string, names []string) ([]string, error) {
+	c, err := config.Load(root)
+	if err != nil {
+		return nil, err
+	}
+	var added []string
+	for _, name := range names {
+		if !slices.Contains(c.TypedData.NpmTrustedDependencies, name) {
+			c.TypedData.NpmTrustedDependencies = append(c.TypedData.NpmTrustedDependencies, name)
+			added = append(added, name)
+		}
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+	slices.Sort(c.TypedData.NpmTrustedDependencies)
+	if err := config.Write(c); err != nil {
+		return nil, err
+	}
+	return added, nil
+}
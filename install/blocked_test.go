@@ -0,0 +1,104 @@
+package install
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/tsukinoko-kun/jmod/config"
+)
+
+// installTestDependency installs the dependency in dir as if it was reached from the registry.
+func installTestDependency(dir string, trusted config.TrustedDependencies) {
+	Run(context.Background(), dir, Options{TrustedDependencies: trusted, dependency: true}, nil)
+}
+
+func resetBlockedScripts() {
+	blockedScriptsMu.Lock()
+	clear(blockedScripts)
+	blockedScriptsMu.Unlock()
+}
+
+func TestUntrustedScriptsBlocked(t *testing.T) {
+	resetBlockedScripts()
+	defer resetBlockedScripts()
+
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"package.json": `{"name":"untrusted-dep","version":"1.0.0","scripts":{"postinstall":"echo ran > ran.txt"}}`,
+	})
+	installTestDependency(dir, config.TrustedDependencies{"other": {}})
+
+	if _, err := os.Stat(filepath.Join(dir, "ran.txt")); err == nil {
+		t.Error("expected the postinstall script of an untrusted dependency to be blocked")
+	}
+	if got := BlockedScripts(); !slices.Equal(got, []string{"untrusted-dep"}) {
+		t.Errorf("BlockedScripts() = %v, want [untrusted-dep]", got)
+	}
+
+	root := t.TempDir()
+	if err := WriteBlockedScripts(root); err != nil {
+		t.Fatal(err)
+	}
+	recorded, err := ReadBlockedScripts(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(recorded, []string{"untrusted-dep"}) {
+		t.Errorf("ReadBlockedScripts() = %v, want [untrusted-dep]", recorded)
+	}
+}
+
+func TestTrustedScriptsRun(t *testing.T) {
+	resetBlockedScripts()
+	defer resetBlockedScripts()
+
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"package.json": `{"name":"trusted-dep","version":"1.0.0","scripts":{"postinstall":"echo ran > ran.txt"}}`,
+	})
+	installTestDependency(dir, config.TrustedDependencies{"trusted-dep": {}})
+
+	if _, err := os.Stat(filepath.Join(dir, "ran.txt")); err != nil {
+		t.Errorf("expected the postinstall script of a trusted dependency to run: %s", err)
+	}
+	if got := BlockedScripts(); len(got) != 0 {
+		t.Errorf("BlockedScripts() = %v, want none", got)
+	}
+}
+
+func TestApproveBuilds(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"package.json": `{"name":"app","trustedDependencies":["esbuild"]}`,
+	})
+
+	added, err := ApproveBuilds(root, []string{"sharp", "esbuild"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(added, []string{"sharp"}) {
+		t.Errorf("ApproveBuilds() added %v, want [sharp]", added)
+	}
+
+	mod, err := config.Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := mod.TypedData.NpmTrustedDependencies; !slices.Equal(got, []string{"esbuild", "sharp"}) {
+		t.Errorf("trustedDependencies = %v, want [esbuild sharp]", got)
+	}
+	if !config.LoadTrustedDependencies(root).Has("sharp") {
+		t.Error("expected sharp to be trusted after approve-builds")
+	}
+
+	added, err = ApproveBuilds(root, []string{"sharp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(added) != 0 {
+		t.Errorf("ApproveBuilds() added %v for an already trusted package", added)
+	}
+}
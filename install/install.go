@@ -4,13 +4,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/tsukinoko-kun/jmod/config"
 	"github.com/tsukinoko-kun/jmod/logger"
-	"github.com/tsukinoko-kun/jmod/meta"
+	"github.com/tsukinoko-kun/jmod/patch"
 	"github.com/tsukinoko-kun/jmod/registry"
 	"github.com/tsukinoko-kun/jmod/scriptsrunner"
 	"github.com/tsukinoko-kun/jmod/statusui"
@@ -68,8 +70,49 @@ func lifecycleScriptKey(packageJsonPath string, namePtr, versionPtr *string, scr
 	return fmt.Sprintf("%s:%s@%s#%s", source, name, version, scriptName)
 }
 
-func Run(ctx context.Context, root string, ignoreScripts bool, dev bool, optional bool, dependencyChain registry.DependencyChain) {
+// Options configures Run.
+type Options struct {
+	IgnoreScripts bool
+	// Dev includes devDependencies, only used for the workspace modules
+	Dev      bool
+	Optional bool
+	// Overrides apply to the dependencies of the installed modules
+	Overrides *config.Overrides
+	// Patches are applied to project-local copies of the matching packages
+	Patches *config.Patches
+	// TrustedDependencies are the dependencies allowed to run lifecycle scripts,
+	// scripts of workspace modules always run
+	TrustedDependencies config.TrustedDependencies
+	// Sandbox restricts the lifecycle scripts of dependencies
+	Sandbox *config.SandboxSettings
+	// ScriptConcurrency limits how many packages run lifecycle scripts at once, defaults to the number of CPUs
+	ScriptConcurrency int
+
+	// dependency is set when installing a package from the registry instead of a workspace module
+	dependency bool
+	// scripts collects the install and postinstall scripts of the whole install, they run once everything is linked.
+	// Preinstall scripts run before the dependencies of their package are linked.
+	scripts *scriptGraph
+	// root is the project the install started at, project-local package copies are stored there
+	root string
+}
+
+func Run(ctx context.Context, root string, opts Options, dependencyChain registry.DependencyChain) {
 	mods := config.FindSubMods(root)
+	ignoreScripts, dev, optional := opts.IgnoreScripts, opts.Dev, opts.Optional
+	if opts.root == "" {
+		opts.root = root
+	}
+	if opts.scripts == nil {
+		opts.scripts = newScriptGraph()
+		defer func() {
+			if ctx.Err() == nil {
+				start := time.Now()
+				opts.scripts.run(ctx, opts.ScriptConcurrency)
+				recordPhase("lifecycle scripts", time.Since(start))
+			}
+		}()
+	}
 
 	wg := sync.WaitGroup{}
 
@@ -84,36 +127,93 @@ modsLoop:
 		wg.Go(func() {
 			mod := modDoc.TypedData
 			dependencyChain := dependencyChain.With(mod.GetFileLocation())
+			runScripts := !ignoreScripts && (!opts.dependency || allowScripts(mod.GetFileLocation(), opts.TrustedDependencies))
+			var sandbox *scriptsrunner.Sandbox
+			if opts.dependency {
+				sandbox = sandboxFor(opts.Sandbox, mod.GetFileLocation())
+			}
 
-			if !ignoreScripts {
-				if err := lifecyclePreinstall(mod.GetFileLocation()); err != nil {
-					err = dependencyChain.Err(err)
-					if optional {
-						logger.Printf("failed to run lifecycle preinstall for %s: %s", mod.GetFileLocation(), err)
-					} else {
-						meta.CancelCause(err)
-					}
+			node := &scriptNode{
+				packageJsonPath: mod.GetFileLocation(),
+				allowed:         runScripts,
+				sandbox:         sandbox,
+				optional:        optional,
+				dependencyChain: dependencyChain,
+			}
+			if runScripts {
+				if err := lifecyclePreinstall(mod.GetFileLocation(), sandbox); err != nil {
+					node.fail("preinstall", err)
 					return
 				}
 			}
+			modKey := scriptNodeKey(filepath.Dir(mod.GetFileLocation()))
+			opts.scripts.add(modKey, node)
 
 			modRoot := filepath.Dir(mod.GetFileLocation())
 			nodeModulesDir := filepath.Join(modRoot, "node_modules")
 			binDir := filepath.Join(nodeModulesDir, ".bin")
 
-			for dependency := range mod.ResolveDependenciesDeep(ctx, dev, optional, dependencyChain) {
-				if err := link(dependency.CachedLocation, filepath.Join(nodeModulesDir, dependency.PackageName)); err != nil {
-					err = dependencyChain.Err(err)
+			for dependency := range mod.ResolveDependenciesDeep(ctx, dev, optional, dependencyChain, opts.Overrides) {
+				location := dependency.CachedLocation
+				if patchFile, ok := opts.Patches.Lookup(dependency.RegistryName, dependency.Version); ok {
+					patched, err := patchedLocation(opts.Patches, dependency, patchFile)
+					if err != nil {
+						if optional {
+							logger.Printf("failed to patch %s: %s", dependency.PackageName, dependencyChain.Err(err))
+						} else {
+							registry.Fail(registry.FailurePatch, dependency.PackageName, dependencyChain, fmt.Errorf("failed to patch %s: %w", dependency.PackageName, err))
+						}
+						return
+					}
+					location = patched
+				}
+				if dependency.Override != nil {
+					recordOverride(dependency.PackageName, dependencyChain, *dependency.Override)
+				}
+				overridden, err := overriddenLocation(dependency, location)
+				if err != nil {
 					if optional {
-						logger.Printf("failed to link %s: %s", dependency.PackageName, err)
+						logger.Printf("failed to copy %s: %s", dependency.PackageName, dependencyChain.Err(err))
 					} else {
-						meta.CancelCause(fmt.Errorf("failed to link %s: %w", dependency.PackageName, err))
+						registry.Fail(registry.FailureLink, dependency.PackageName, dependencyChain, fmt.Errorf("failed to copy %s for its overrides: %w", dependency.PackageName, err))
 					}
 					return
 				}
+				location = overridden
+				if !ignoreScripts && allowScripts(filepath.Join(location, "package.json"), opts.TrustedDependencies) {
+					native, err := nativeLocation(opts.root, dependency, location)
+					if err != nil {
+						if optional {
+							logger.Printf("failed to copy %s: %s", dependency.PackageName, dependencyChain.Err(err))
+						} else {
+							registry.Fail(registry.FailureLink, dependency.PackageName, dependencyChain, fmt.Errorf("failed to copy %s for its native build: %w", dependency.PackageName, err))
+						}
+						return
+					}
+					location = native
+				}
+				if err := link(location, filepath.Join(nodeModulesDir, dependency.PackageName)); err != nil {
+					if optional {
+						logger.Printf("failed to link %s: %s", dependency.PackageName, dependencyChain.Err(err))
+					} else {
+						registry.Fail(registry.FailureLink, dependency.PackageName, dependencyChain, fmt.Errorf("failed to link %s: %w", dependency.PackageName, err))
+					}
+					return
+				}
+				opts.scripts.addEdge(modKey, scriptNodeKey(location))
 				// recursive install - only if not already processed
-				if shouldProcessPackage(dependency.CachedLocation) {
-					Run(ctx, dependency.CachedLocation, ignoreScripts, false, optional, dependencyChain)
+				if shouldProcessPackage(location) {
+					Run(ctx, location, Options{
+						IgnoreScripts:       ignoreScripts,
+						Optional:            optional,
+						Overrides:           dependency.Overrides,
+						Patches:             opts.Patches,
+						TrustedDependencies: opts.TrustedDependencies,
+						Sandbox:             opts.Sandbox,
+						dependency:          true,
+						scripts:             opts.scripts,
+						root:                opts.root,
+					}, dependencyChain)
 				}
 				select {
 				case <-ctx.Done():
@@ -121,46 +221,55 @@ modsLoop:
 				default:
 				}
 				// setup executables
-				if bins, err := config.ResolveBins(ctx, dependency.CachedLocation); err != nil {
-					err = dependencyChain.Err(err)
+				if bins, err := config.ResolveBins(ctx, location); err != nil {
 					if optional {
-						logger.Printf("failed to resolve bins for %s: %s", dependency.CachedLocation, err)
+						logger.Printf("failed to resolve bins for %s: %s", location, dependencyChain.Err(err))
 					} else {
-						meta.CancelCause(fmt.Errorf("failed to resolve bins for %s: %w", dependency.CachedLocation, err))
+						registry.Fail(registry.FailureLink, dependency.PackageName, dependencyChain, fmt.Errorf("failed to resolve bins for %s: %w", location, err))
 					}
 					return
 				} else {
 					for _, bin := range bins {
 						if err := link(bin.BinPath, filepath.Join(binDir, bin.BinName)); err != nil {
-							err = dependencyChain.Err(err)
 							if optional {
-								logger.Printf("failed to link %s: %s", bin.BinName, err)
+								logger.Printf("failed to link %s: %s", bin.BinName, dependencyChain.Err(err))
 							} else {
-								meta.CancelCause(fmt.Errorf("failed to link %s: %w", bin.BinName, err))
+								registry.Fail(registry.FailureLink, dependency.PackageName, dependencyChain, fmt.Errorf("failed to link %s: %w", bin.BinName, err))
 							}
 							return
 						}
 					}
 				}
 			}
-
-			if !ignoreScripts {
-				if err := lifecyclePostinstall(mod.GetFileLocation()); err != nil {
-					err = dependencyChain.Err(err)
-					if optional {
-						logger.Printf("failed to run lifecycle postinstall for %s: %s", mod.GetFileLocation(), err)
-					} else {
-						meta.CancelCause(err)
-					}
-					return
-				}
-			}
 		})
 	}
 
 	wg.Wait()
 }
 
+// patchedLocation returns a project-local copy of the dependency with the patch applied.
+// The copy lives in the node_modules of the module declaring the patch
+// and its name contains the patch hash, so changed patches result in a new copy.
+func patchedLocation(patches *config.Patches, dependency config.ResolvedDependency, patchFile string) (string, error) {
+	diff, err := os.ReadFile(patchFile)
+	if err != nil {
+		return "", fmt.Errorf("read patch: %w", err)
+	}
+	hash := patch.Hash(diff)
+	dest := filepath.Join(
+		patches.Root(),
+		"node_modules",
+		".jmod",
+		"patched",
+		fmt.Sprintf("%s@%s_%s", strings.ReplaceAll(dependency.RegistryName, "/", "+"), dependency.Version, hash),
+	)
+	if err := patch.PatchedCopy(dependency.CachedLocation, diff, dest); err != nil {
+		return "", err
+	}
+	logger.Printf("patched %s@%s (%s)", dependency.RegistryName, dependency.Version, hash)
+	return dest, nil
+}
+
 func shouldProcessPackage(cachedLocation string) bool {
 	// Normalize the path - try to resolve symlinks once
 	normalized := filepath.Clean(cachedLocation)
@@ -199,18 +308,22 @@ func shouldProcessPackage(cachedLocation string) bool {
 	return true
 }
 
-func lifecyclePreinstall(packageJsonPath string) error {
-	return runLifecycleScript(packageJsonPath, "preinstall")
+func lifecyclePreinstall(packageJsonPath string, sandbox *scriptsrunner.Sandbox) error {
+	return runLifecycleScript(packageJsonPath, "preinstall", sandbox)
 }
 
-func lifecyclePostinstall(packageJsonPath string) error {
-	if err := runLifecycleScript(packageJsonPath, "install"); err != nil {
+func lifecyclePostinstall(packageJsonPath string, sandbox *scriptsrunner.Sandbox) error {
+	if needsNodeGyp(packageJsonPath) {
+		if err := runNativeBuild(packageJsonPath, sandbox); err != nil {
+			return err
+		}
+	} else if err := runLifecycleScript(packageJsonPath, "install", sandbox); err != nil {
 		return err
 	}
-	return runLifecycleScript(packageJsonPath, "postinstall")
+	return runLifecycleScript(packageJsonPath, "postinstall", sandbox)
 }
 
-func runLifecycleScript(packageJsonPath, scriptName string) error {
+func runLifecycleScript(packageJsonPath, scriptName string, sandbox *scriptsrunner.Sandbox) error {
 	// Get package name for status key
 	pj, err := config.GetPackageJsonForLifecycle(packageJsonPath)
 	key := lifecycleScriptKey(packageJsonPath, pj.Name, pj.Version, scriptName)
@@ -218,6 +331,10 @@ func runLifecycleScript(packageJsonPath, scriptName string) error {
 		return nil
 	}
 
+	if err == nil && (pj.Scripts == nil || (*pj.Scripts)[scriptName] == "") {
+		return nil
+	}
+
 	statusKey := packageJsonPath
 	if err == nil && pj.Name != nil {
 		statusKey = fmt.Sprintf("script:%s", pj.Identifier())
@@ -227,7 +344,7 @@ func runLifecycleScript(packageJsonPath, scriptName string) error {
 		Text: fmt.Sprintf("🔧 Running %s script for %s", scriptName, pj.Identifier()),
 	})
 
-	if err := scriptsrunner.Run(packageJsonPath, scriptName, nil, "install"); err != nil {
+	if err := scriptsrunner.RunSandboxed(packageJsonPath, scriptName, nil, "install", sandbox); err != nil {
 		if errors.Is(err, scriptsrunner.ErrScriptNotFound) {
 			// Clear status if script not found (not an error)
 			statusui.Clear(statusKey)
@@ -242,6 +359,8 @@ func runLifecycleScript(packageJsonPath, scriptName string) error {
 		return fmt.Errorf("failed to run %s script: %w", scriptName, err)
 	}
 
+	scriptsRun.Add(1)
+
 	// Success - clear after a moment
 	statusui.Set(statusKey, statusui.SuccessStatus{
 		Message: fmt.Sprintf("Completed %s script", scriptName),
@@ -8,6 +8,7 @@ import (
 )
 
 func link(original, link string) error {
+	defer linkTimer.Start()()
 	linkMut.Lock()
 	defer linkMut.Unlock()
 
@@ -9,6 +9,7 @@ import (
 )
 
 func link(original, link string) error {
+	defer linkTimer.Start()()
 	linkMut.Lock()
 	defer linkMut.Unlock()
 
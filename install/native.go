@@ -0,0 +1,184 @@
+package install
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/tsukinoko-kun/jmod/config"
+	"github.com/tsukinoko-kun/jmod/logger"
+	"github.com/tsukinoko-kun/jmod/patch"
+	"github.com/tsukinoko-kun/jmod/registry"
+	"github.com/tsukinoko-kun/jmod/scriptsrunner"
+	"github.com/tsukinoko-kun/jmod/statusui"
+)
+
+// nativeBuildStamp is written into the build directory of a package
+// to remember which node ABI and platform it was built for
+const nativeBuildStamp = ".jmod-native"
+
+// needsNodeGyp reports whether npm would implicitly run `node-gyp rebuild` for the package,
+// which is the case for packages with a binding.gyp, no "gypfile": false and neither an install nor a preinstall script.
+func needsNodeGyp(packageJsonPath string) bool {
+	if _, err := os.Stat(filepath.Join(filepath.Dir(packageJsonPath), "binding.gyp")); err != nil {
+		return false
+	}
+	pj, err := config.GetPackageJsonForLifecycle(packageJsonPath)
+	if err != nil || (pj.Gypfile != nil && !*pj.Gypfile) {
+		return false
+	}
+	if pj.Scripts != nil {
+		if _, ok := (*pj.Scripts)["install"]; ok {
+			return false
+		}
+		if _, ok := (*pj.Scripts)["preinstall"]; ok {
+			return false
+		}
+	}
+	return true
+}
+
+// nativeBuildKey identifies the node ABI and platform a native addon is built for.
+func nativeBuildKey() (string, bool) {
+	abi, ok := scriptsrunner.NodeABI()
+	if !ok {
+		return "", false
+	}
+	p := registry.TargetPlatform
+	key := fmt.Sprintf("node-%s-%s-%s", abi, p.OS, p.CPU)
+	if p.Libc != "" {
+		key += "-" + p.Libc
+	}
+	return key, true
+}
+
+// runNativeBuild runs buildNativeAddon once per package and reports its progress.
+func runNativeBuild(packageJsonPath string, sandbox *scriptsrunner.Sandbox) error {
+	pj, _ := config.GetPackageJsonForLifecycle(packageJsonPath)
+	if !shouldRunLifecycleScript(lifecycleScriptKey(packageJsonPath, pj.Name, pj.Version, "install")) {
+		return nil
+	}
+	statusKey := fmt.Sprintf("script:%s", packageJsonPath)
+	statusui.Set(statusKey, statusui.TextStatus{
+		Text: fmt.Sprintf("🔧 Building native addon for %s", pj.Identifier()),
+	})
+	defer statusui.Clear(statusKey)
+	if err := buildNativeAddon(packageJsonPath, sandbox); err != nil {
+		return fmt.Errorf("failed to build native addon for %s: %w", pj.Identifier(), err)
+	}
+	scriptsRun.Add(1)
+	return nil
+}
+
+// nativeCopy is a project-local copy of a registry package that is built for a node ABI and platform.
+type nativeCopy struct {
+	key string
+	// cached is the directory the build is stored in for other projects
+	cached string
+	name   string
+}
+
+// nativeCopies maps the directories created by nativeLocation to their build.
+var nativeCopies sync.Map
+
+// nativeLocation returns a project-local copy of the registry package at location
+// if it is built with node-gyp. The shared cache entry is linked by every project,
+// regardless of the node version it uses, so builds must never end up there.
+// The copy is keyed by the node ABI and platform, switching node versions links another copy.
+func nativeLocation(root string, dependency config.ResolvedDependency, location string) (string, error) {
+	source, _, _, inCache := registry.PackageIdentifierFromPath(location)
+	if !inCache || !needsNodeGyp(filepath.Join(location, "package.json")) {
+		return location, nil
+	}
+	key, ok := nativeBuildKey()
+	if !ok {
+		return location, nil
+	}
+	id := fmt.Sprintf("%s@%s", strings.ReplaceAll(dependency.RegistryName, "/", "+"), dependency.Version)
+	dest := filepath.Join(root, "node_modules", ".jmod", "native", id+"_"+key)
+	if err := patch.LocalCopy(location, dest); err != nil {
+		return "", err
+	}
+	resolved := dest
+	if r, err := filepath.EvalSymlinks(dest); err == nil {
+		resolved = r
+	}
+	nativeCopies.Store(resolved, nativeCopy{
+		key:    key,
+		cached: filepath.Join(registry.GetNativeBuildCacheLocation(), source, id, key),
+		name:   dependency.RegistryName + "@" + dependency.Version,
+	})
+	return dest, nil
+}
+
+// buildNativeAddon runs node-gyp for the package at packageJsonPath.
+// Builds of registry packages are cached per node ABI and platform
+// so switching between node versions does not rebuild them for every project.
+func buildNativeAddon(packageJsonPath string, sandbox *scriptsrunner.Sandbox) error {
+	dir := filepath.Dir(packageJsonPath)
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	if _, _, _, inCache := registry.PackageIdentifierFromPath(dir); inCache {
+		return fmt.Errorf("refusing to build inside the shared package cache, the node ABI is unknown")
+	}
+	v, ok := nativeCopies.Load(dir)
+	if !ok {
+		// patched copies, overridden copies and workspace packages are built in place
+		return scriptsrunner.RunNodeGyp(packageJsonPath, sandbox)
+	}
+	c := v.(nativeCopy)
+	buildDir := filepath.Join(dir, "build")
+
+	if stamp, err := os.ReadFile(filepath.Join(buildDir, nativeBuildStamp)); err == nil && string(stamp) == c.key {
+		return nil
+	}
+
+	if _, err := os.Stat(c.cached); err == nil {
+		if err := os.RemoveAll(buildDir); err != nil {
+			return fmt.Errorf("remove stale native build: %w", err)
+		}
+		if err := patch.CopyDir(c.cached, buildDir); err != nil {
+			return fmt.Errorf("restore native build of %s: %w", c.name, err)
+		}
+		logger.Printf("restored native build of %s (%s)", c.name, c.key)
+		return nil
+	}
+
+	if err := scriptsrunner.RunNodeGyp(packageJsonPath, sandbox); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(buildDir, nativeBuildStamp), []byte(c.key), 0o644); err != nil {
+		logger.Warnf("failed to cache native build of %s: %s", c.name, err)
+		return nil
+	}
+	if err := storeNativeBuild(buildDir, c.cached); err != nil {
+		logger.Warnf("failed to cache native build of %s: %s", c.name, err)
+	}
+	return nil
+}
+
+func storeNativeBuild(buildDir, cached string) error {
+	parent := filepath.Dir(cached)
+	if err := os.MkdirAll(parent, 0o755); err != nil {
+		return err
+	}
+	staging, err := os.MkdirTemp(parent, ".build-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+	if err := patch.CopyDir(buildDir, staging); err != nil {
+		return err
+	}
+	if err := os.Rename(staging, cached); err != nil {
+		// another process stored the same build concurrently
+		if _, statErr := os.Stat(cached); statErr == nil {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package install
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestNeedsNodeGyp(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		packageJson string
+		bindingGyp  bool
+		want        bool
+	}{
+		{"binding.gyp", `{"name":"addon"}`, true, true},
+		{"no binding.gyp", `{"name":"addon"}`, false, false},
+		{"gypfile false", `{"name":"addon","gypfile":false}`, true, false},
+		{"gypfile true", `{"name":"addon","gypfile":true}`, true, true},
+		{"install script", `{"name":"addon","scripts":{"install":"prebuild-install"}}`, true, false},
+		{"preinstall script", `{"name":"addon","scripts":{"preinstall":"node setup.js"}}`, true, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			files := map[string]string{"package.json": tc.packageJson}
+			if tc.bindingGyp {
+				files["binding.gyp"] = `{"targets":[]}`
+			}
+			writeTestFiles(t, dir, files)
+			if got := needsNodeGyp(filepath.Join(dir, "package.json")); got != tc.want {
+				t.Errorf("needsNodeGyp() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package install
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/tsukinoko-kun/jmod/config"
+	"github.com/tsukinoko-kun/jmod/logger"
+	"github.com/tsukinoko-kun/jmod/patch"
+	"github.com/tsukinoko-kun/jmod/registry"
+)
+
+// overriddenLocation returns a project-local copy of the package at location
+// if its dependencies are resolved with overrides. The node_modules of a shared cache entry
+// is linked by every project, overrides of one project must not end up there.
+// The copy is keyed by the fingerprint of the overrides, so differently scoped rules get their own copy,
+// and by the location of patched copies, so a changed patch results in a new copy.
+// Packages without dependencies and local packages are used as they are.
+func overriddenLocation(dependency config.ResolvedDependency, location string) (string, error) {
+	fingerprint := dependency.Overrides.Fingerprint()
+	if fingerprint == "" || dependency.Version == "" || !hasDependencies(location) {
+		return location, nil
+	}
+	key := fingerprint
+	if location != dependency.CachedLocation {
+		key += "_" + patch.Hash([]byte(location))
+	}
+	dest := filepath.Join(
+		dependency.Overrides.Root(),
+		"node_modules",
+		".jmod",
+		"overridden",
+		fmt.Sprintf("%s@%s_%s", strings.ReplaceAll(dependency.RegistryName, "/", "+"), dependency.Version, key),
+	)
+	if err := patch.LocalCopy(location, dest); err != nil {
+		return "", err
+	}
+	return dest, nil
+}
+
+func hasDependencies(packageDir string) bool {
+	data, err := os.ReadFile(filepath.Join(packageDir, "package.json"))
+	if err != nil {
+		return false
+	}
+	var pj struct {
+		Dependencies         map[string]string `json:"dependencies"`
+		OptionalDependencies map[string]string `json:"optionalDependencies"`
+	}
+	if err := json.Unmarshal(data, &pj); err != nil {
+		// the resolver decides what is wrong with it
+		return true
+	}
+	return len(pj.Dependencies) != 0 || len(pj.OptionalDependencies) != 0
+}
+
+// AppliedOverride is an override that replaced the requested version of a dependency.
+type AppliedOverride struct {
+	Name string `json:"name"`
+	// Chain lists the packages that lead to the dependency, starting at the workspace module
+	Chain []string `json:"chain"`
+	config.Override
+}
+
+var (
+	appliedOverridesMu sync.Mutex
+	appliedOverrides   []AppliedOverride
+)
+
+func recordOverride(name string, chain registry.DependencyChain, o config.Override) {
+	names := make([]string, len(chain))
+	for i, packageJsonPath := range chain {
+		dir := filepath.Dir(packageJsonPath)
+		if _, n, v, ok := registry.PackageIdentifierFromPath(dir); ok {
+			names[i] = n + "@" + v
+		} else if pj, err := config.GetPackageJsonForLifecycle(packageJsonPath); err == nil && pj.Name != nil {
+			names[i] = pj.Identifier()
+		} else {
+			names[i] = filepath.Base(dir)
+		}
+	}
+	appliedOverridesMu.Lock()
+	appliedOverrides = append(appliedOverrides, AppliedOverride{Name: name, Chain: names, Override: o})
+	appliedOverridesMu.Unlock()
+}
+
+func overridesFile(root string) string {
+	return filepath.Join(root, "node_modules", ".jmod", "overrides.json")
+}
+
+// WriteOverrides stores the overrides applied by the installs so far for ReadOverrides.
+func WriteOverrides(root string) error {
+	appliedOverridesMu.Lock()
+	applied := slices.Clone(appliedOverrides)
+	appliedOverridesMu.Unlock()
+	slices.SortFunc(applied, func(a, b AppliedOverride) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), slices.Compare(a.Chain, b.Chain))
+	})
+	applied = slices.CompactFunc(applied, func(a, b AppliedOverride) bool {
+		return a.Name == b.Name && slices.Equal(a.Chain, b.Chain)
+	})
+	if applied == nil {
+		applied = []AppliedOverride{}
+	}
+	data, err := json.MarshalIndent(applied, "", "  ")
+	if err != nil {
+		return err
+	}
+	file := overridesFile(root)
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0o644)
+}
+
+// ReadOverrides returns the overrides applied by the last install at root.
+func ReadOverrides(root string) ([]AppliedOverride, error) {
+	data, err := os.ReadFile(overridesFile(root))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var applied []AppliedOverride
+	if err := json.Unmarshal(data, &applied); err != nil {
+		logger.Printf("failed to decode %s: %s", overridesFile(root), err)
+		return nil, err
+	}
+	return applied, nil
+}
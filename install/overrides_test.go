@@ -0,0 +1,90 @@
+package install
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tsukinoko-kun/jmod/config"
+	"github.com/tsukinoko-kun/jmod/patch"
+)
+
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestOverriddenPatchedCopy(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"package.json": `{"name":"app","overrides":{"dep":"2.0.0"},"patchedDependencies":{"pkg@1.0.0":"patches/pkg@1.0.0.patch"}}`,
+	})
+	mod, err := config.Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	overrides, err := mod.TypedData.Overrides()
+	if err != nil {
+		t.Fatal(err)
+	}
+	patches := mod.TypedData.Patches()
+
+	cached := filepath.Join(t.TempDir(), "pkg")
+	writeTestFiles(t, cached, map[string]string{
+		"package.json": `{"name":"pkg","version":"1.0.0","dependencies":{"dep":"^1.0.0"}}`,
+		"index.js":     "module.exports = 1\n",
+	})
+	dependency := config.ResolvedDependency{
+		PackageName:    "pkg",
+		RegistryName:   "pkg",
+		Version:        "1.0.0",
+		CachedLocation: cached,
+		Overrides:      overrides,
+	}
+
+	apply := func(content string) string {
+		t.Helper()
+		edited := filepath.Join(t.TempDir(), "pkg")
+		writeTestFiles(t, edited, map[string]string{
+			"package.json": `{"name":"pkg","version":"1.0.0","dependencies":{"dep":"^1.0.0"}}`,
+			"index.js":     content,
+		})
+		diff, err := patch.DiffDirs(cached, edited)
+		if err != nil {
+			t.Fatal(err)
+		}
+		patchFile, _ := patches.Lookup("pkg", "1.0.0")
+		writeTestFiles(t, root, map[string]string{"patches/pkg@1.0.0.patch": string(diff)})
+
+		patched, err := patchedLocation(patches, dependency, patchFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		location, err := overriddenLocation(dependency, patched)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(filepath.Join(location, "index.js"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != content {
+			t.Errorf("overridden copy has %q, want %q", got, content)
+		}
+		return location
+	}
+
+	first := apply("module.exports = 2\n")
+	second := apply("module.exports = 3\n")
+	if first == second {
+		t.Errorf("expected a changed patch to result in a new overridden copy, both are %s", first)
+	}
+}
@@ -0,0 +1,45 @@
+package install
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tsukinoko-kun/jmod/config"
+	"github.com/tsukinoko-kun/jmod/scriptsrunner"
+)
+
+// sandboxFor returns the sandbox for the lifecycle scripts of the dependency at packageJsonPath
+// or nil if the sandbox is disabled for it.
+func sandboxFor(settings *config.SandboxSettings, packageJsonPath string) *scriptsrunner.Sandbox {
+	if settings == nil || !settings.Enabled {
+		return nil
+	}
+	sandbox := &scriptsrunner.Sandbox{}
+	pj, err := config.GetPackageJsonForLifecycle(packageJsonPath)
+	if err != nil || pj.Name == nil {
+		return sandbox
+	}
+	exception, ok := settings.Packages[*pj.Name]
+	if !ok {
+		return sandbox
+	}
+	if exception.Disabled {
+		return nil
+	}
+	sandbox.Network = exception.Network
+	sandbox.Env = exception.Env
+	for _, p := range exception.Write {
+		if rest, ok := strings.CutPrefix(p, "~/"); ok {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				continue
+			}
+			p = filepath.Join(home, rest)
+		}
+		if filepath.IsAbs(p) {
+			sandbox.Write = append(sandbox.Write, p)
+		}
+	}
+	return sandbox
+}
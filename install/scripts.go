@@ -0,0 +1,228 @@
+package install
+
+import (
+	"context"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sync"
+
+	"github.com/tsukinoko-kun/jmod/config"
+	"github.com/tsukinoko-kun/jmod/logger"
+	"github.com/tsukinoko-kun/jmod/registry"
+	"github.com/tsukinoko-kun/jmod/scriptsrunner"
+)
+
+// scriptNode is a package in the install graph.
+// Packages without allowed scripts are part of the graph to keep transitive ordering.
+type scriptNode struct {
+	packageJsonPath string
+	allowed         bool
+	sandbox         *scriptsrunner.Sandbox
+	optional        bool
+	dependencyChain registry.DependencyChain
+	deps            map[string]struct{}
+}
+
+// scriptGraph collects the lifecycle scripts of an install
+// so they can run after linking, dependencies before their dependents.
+type scriptGraph struct {
+	mu    sync.Mutex
+	nodes map[string]*scriptNode
+	// exec runs the scripts of a single package
+	exec func(node *scriptNode, ctx context.Context)
+}
+
+func newScriptGraph() *scriptGraph {
+	return &scriptGraph{nodes: make(map[string]*scriptNode), exec: (*scriptNode).run}
+}
+
+// scriptNodeKey identifies a package directory regardless of the symlinks it is reached through.
+func scriptNodeKey(dir string) string {
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		return resolved
+	}
+	return filepath.Clean(dir)
+}
+
+// add registers a package, the first registration wins.
+func (g *scriptGraph) add(key string, node *scriptNode) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if existing, ok := g.nodes[key]; ok {
+		// a package required by a non-optional dependent is not optional
+		existing.optional = existing.optional && node.optional
+		return
+	}
+	node.deps = make(map[string]struct{})
+	g.nodes[key] = node
+}
+
+// addEdge records that the package at from depends on the package at to.
+func (g *scriptGraph) addEdge(from, to string) {
+	if from == to {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if node, ok := g.nodes[from]; ok {
+		node.deps[to] = struct{}{}
+	}
+}
+
+// run executes the scripts in topological order with at most concurrency packages at once.
+// Packages that depend on each other in a cycle are not ordered among themselves.
+func (g *scriptGraph) run(ctx context.Context, concurrency int) {
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// the scripts run on the graph of the strongly connected components,
+	// a cycle starts once everything it depends on is done and its dependents wait for all of it
+	component := g.components()
+	members := make(map[int][]string)
+	for key := range g.nodes {
+		members[component[key]] = append(members[component[key]], key)
+	}
+	pending := make(map[int]int, len(members))
+	dependents := make(map[int][]int, len(members))
+	seen := make(map[[2]int]struct{})
+	for key, node := range g.nodes {
+		for dep := range node.deps {
+			if _, ok := g.nodes[dep]; !ok {
+				continue
+			}
+			from, to := component[key], component[dep]
+			if from == to {
+				logger.Printf("dependency cycle between %s and %s, their scripts run in no particular order", node.packageJsonPath, g.nodes[dep].packageJsonPath)
+				continue
+			}
+			if _, ok := seen[[2]int{from, to}]; ok {
+				continue
+			}
+			seen[[2]int{from, to}] = struct{}{}
+			pending[from]++
+			dependents[to] = append(dependents[to], from)
+		}
+	}
+	var ready []string
+	for c, keys := range members {
+		if pending[c] == 0 {
+			ready = append(ready, keys...)
+		}
+	}
+	slices.Sort(ready)
+
+	remaining := make(map[int]int, len(members))
+	for c, keys := range members {
+		remaining[c] = len(keys)
+	}
+	finished := make(chan string)
+	running := 0
+	done := 0
+	for done < len(g.nodes) {
+		for running < concurrency && len(ready) > 0 && ctx.Err() == nil {
+			key := ready[0]
+			ready = ready[1:]
+			running++
+			go func() {
+				g.exec(g.nodes[key], ctx)
+				finished <- key
+			}()
+		}
+		if running == 0 {
+			// canceled
+			return
+		}
+
+		key := <-finished
+		running--
+		done++
+		c := component[key]
+		remaining[c]--
+		if remaining[c] > 0 {
+			continue
+		}
+		for _, dependent := range dependents[c] {
+			pending[dependent]--
+			if pending[dependent] == 0 {
+				ready = append(ready, members[dependent]...)
+			}
+		}
+	}
+}
+
+// components assigns every package to its strongly connected component (Tarjan's algorithm).
+// Packages in the same component depend on each other in a cycle.
+func (g *scriptGraph) components() map[string]int {
+	index := make(map[string]int, len(g.nodes))
+	lowlink := make(map[string]int, len(g.nodes))
+	onStack := make(map[string]bool)
+	component := make(map[string]int, len(g.nodes))
+	var stack []string
+	next, nextComponent := 0, 0
+
+	var connect func(key string)
+	connect = func(key string) {
+		index[key] = next
+		lowlink[key] = next
+		next++
+		stack = append(stack, key)
+		onStack[key] = true
+		for dep := range g.nodes[key].deps {
+			if _, ok := g.nodes[dep]; !ok {
+				continue
+			}
+			if _, visited := index[dep]; !visited {
+				connect(dep)
+				lowlink[key] = min(lowlink[key], lowlink[dep])
+			} else if onStack[dep] {
+				lowlink[key] = min(lowlink[key], index[dep])
+			}
+		}
+		if lowlink[key] == index[key] {
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component[top] = nextComponent
+				if top == key {
+					break
+				}
+			}
+			nextComponent++
+		}
+	}
+	for key := range g.nodes {
+		if _, visited := index[key]; !visited {
+			connect(key)
+		}
+	}
+	return component
+}
+
+// run runs the install and postinstall lifecycle scripts of the package,
+// the preinstall script already ran before its dependencies were linked.
+func (n *scriptNode) run(ctx context.Context) {
+	if !n.allowed || ctx.Err() != nil {
+		return
+	}
+	if err := lifecyclePostinstall(n.packageJsonPath, n.sandbox); err != nil {
+		n.fail("postinstall", err)
+	}
+}
+
+func (n *scriptNode) fail(stage string, err error) {
+	if n.optional {
+		logger.Printf("failed to run lifecycle %s for %s: %s", stage, n.packageJsonPath, n.dependencyChain.Err(err))
+	} else {
+		name := n.packageJsonPath
+		if pj, err := config.GetPackageJsonForLifecycle(n.packageJsonPath); err == nil && pj.Name != nil {
+			name = pj.Identifier()
+		}
+		registry.Fail(registry.FailureScript, name, n.dependencyChain, err)
+	}
+}
@@ -0,0 +1,135 @@
+package install
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testScriptGraph(edges map[string][]string) (*scriptGraph, func() []string) {
+	g := newScriptGraph()
+	for key := range edges {
+		g.add(key, &scriptNode{packageJsonPath: key, allowed: true})
+	}
+	for key, deps := range edges {
+		for _, dep := range deps {
+			g.addEdge(key, dep)
+		}
+	}
+	var mu sync.Mutex
+	var order []string
+	g.exec = func(node *scriptNode, ctx context.Context) {
+		mu.Lock()
+		order = append(order, node.packageJsonPath)
+		mu.Unlock()
+	}
+	return g, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(order)
+	}
+}
+
+func assertBefore(t *testing.T, order []string, first, second string) {
+	t.Helper()
+	i, j := slices.Index(order, first), slices.Index(order, second)
+	if i < 0 || j < 0 {
+		t.Fatalf("expected %s and %s to run, got %v", first, second, order)
+	}
+	if i > j {
+		t.Errorf("expected %s to run before %s, got %v", first, second, order)
+	}
+}
+
+func TestScriptGraphOrder(t *testing.T) {
+	g, order := testScriptGraph(map[string][]string{
+		"app":   {"lib", "util"},
+		"lib":   {"addon"},
+		"util":  {"addon"},
+		"addon": nil,
+		"other": nil,
+	})
+	g.run(context.Background(), 4)
+
+	got := order()
+	if len(got) != 5 {
+		t.Fatalf("expected every package to run once, got %v", got)
+	}
+	assertBefore(t, got, "addon", "lib")
+	assertBefore(t, got, "addon", "util")
+	assertBefore(t, got, "lib", "app")
+	assertBefore(t, got, "util", "app")
+}
+
+func TestScriptGraphConcurrency(t *testing.T) {
+	edges := make(map[string][]string)
+	for _, key := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		edges[key] = nil
+	}
+	g, _ := testScriptGraph(edges)
+
+	var running, peak, ran atomic.Int32
+	g.exec = func(node *scriptNode, ctx context.Context) {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		running.Add(-1)
+		ran.Add(1)
+	}
+	g.run(context.Background(), 3)
+
+	if ran.Load() != 8 {
+		t.Errorf("expected 8 packages to run, got %d", ran.Load())
+	}
+	if peak.Load() > 3 {
+		t.Errorf("expected at most 3 packages at once, got %d", peak.Load())
+	}
+	if peak.Load() < 2 {
+		t.Errorf("expected independent packages to run concurrently, got %d at once", peak.Load())
+	}
+}
+
+func TestScriptGraphCycle(t *testing.T) {
+	g, order := testScriptGraph(map[string][]string{
+		"app": {"a"},
+		"a":   {"b"},
+		"b":   {"a", "dep"},
+		"dep": nil,
+	})
+
+	component := g.components()
+	if component["a"] != component["b"] {
+		t.Errorf("expected a and b in the same component, got %v", component)
+	}
+	if component["app"] == component["a"] || component["dep"] == component["a"] {
+		t.Errorf("expected app and dep outside the cycle, got %v", component)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		g.run(context.Background(), 2)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("run did not finish, the cycle blocks it")
+	}
+
+	got := order()
+	if len(got) != 4 {
+		t.Fatalf("expected every package to run once, got %v", got)
+	}
+	assertBefore(t, got, "dep", "a")
+	assertBefore(t, got, "dep", "b")
+	assertBefore(t, got, "a", "app")
+	assertBefore(t, got, "b", "app")
+}
@@ -0,0 +1,181 @@
+package install
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tsukinoko-kun/jmod/config"
+	"github.com/tsukinoko-kun/jmod/registry"
+)
+
+// Phase is a timed part of an install.
+type Phase struct {
+	Name     string
+	Duration time.Duration
+}
+
+// Summary describes the work of the installs since the program started.
+type Summary struct {
+	registry.FetchStats
+	// Scripts is the number of lifecycle scripts and native builds that ran
+	Scripts int
+	// Phases overlap, resolving, fetching and linking happen concurrently
+	Phases []Phase
+}
+
+var (
+	scriptsRun atomic.Int64
+	linkTimer  registry.PhaseTimer
+	phasesMu   sync.Mutex
+	phases     []Phase
+)
+
+func recordPhase(name string, d time.Duration) {
+	phasesMu.Lock()
+	phases = append(phases, Phase{Name: name, Duration: d})
+	phasesMu.Unlock()
+}
+
+// GetSummary returns the summary of the installs so far.
+func GetSummary() Summary {
+	phasesMu.Lock()
+	defer phasesMu.Unlock()
+	stats := registry.Stats()
+	return Summary{
+		FetchStats: stats,
+		Scripts:    int(scriptsRun.Load()),
+		Phases: append([]Phase{
+			{Name: "resolve", Duration: stats.ResolveTime},
+			{Name: "fetch", Duration: stats.FetchTime},
+			{Name: "link", Duration: linkTimer.Elapsed()},
+		}, phases...),
+	}
+}
+
+// Snapshot maps the modules of a project, relative to the project root,
+// to the versions of their direct dependencies.
+type Snapshot map[string]map[string]string
+
+// TakeSnapshot reads the installed versions of the direct dependencies of every module at root.
+func TakeSnapshot(root string) Snapshot {
+	snapshot := Snapshot{}
+	for _, modDoc := range config.FindSubMods(root) {
+		mod := modDoc.TypedData
+		modRoot := filepath.Dir(mod.GetFileLocation())
+		rel, err := filepath.Rel(root, modRoot)
+		if err != nil {
+			continue
+		}
+		deps := map[string]string{}
+		for _, declared := range []map[string]string{mod.NpmDependencies, mod.NpmDevDependencies, mod.NpmOptionalDependencies} {
+			for name, spec := range declared {
+				pj, err := config.GetPackageJsonForLifecycle(filepath.Join(modRoot, "node_modules", name, "package.json"))
+				if err != nil {
+					continue
+				}
+				// local packages often have no version, their spec identifies them instead
+				version := spec
+				if pj.Version != nil && *pj.Version != "" {
+					version = *pj.Version
+				}
+				deps[name] = version
+			}
+		}
+		snapshot[filepath.ToSlash(rel)] = deps
+	}
+	return snapshot
+}
+
+func snapshotFile(root string) string {
+	return filepath.Join(root, "node_modules", ".jmod", "installed.json")
+}
+
+// ReadSnapshot returns the snapshot written by the last install at root.
+// A missing snapshot is empty, so everything counts as added.
+func ReadSnapshot(root string) (Snapshot, error) {
+	data, err := os.ReadFile(snapshotFile(root))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Snapshot{}, nil
+		}
+		return nil, err
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// WriteSnapshot stores snapshot for the summary of the next install.
+func WriteSnapshot(root string, snapshot Snapshot) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	file := snapshotFile(root)
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0o644)
+}
+
+// Change is an added, removed or updated direct dependency.
+// From is empty for added and To is empty for removed dependencies.
+type Change struct {
+	Name string
+	From string
+	To   string
+}
+
+// ModuleChanges are the changed direct dependencies of a module.
+type ModuleChanges struct {
+	Module  string
+	Changes []Change
+}
+
+// Diff returns the changed dependencies per module, sorted by module and dependency name.
+func Diff(before, after Snapshot) []ModuleChanges {
+	modules := make(map[string]struct{}, len(after))
+	for module := range before {
+		modules[module] = struct{}{}
+	}
+	for module := range after {
+		modules[module] = struct{}{}
+	}
+
+	var diff []ModuleChanges
+	for module := range modules {
+		var changes []Change
+		for name, from := range before[module] {
+			if to, ok := after[module][name]; !ok {
+				changes = append(changes, Change{Name: name, From: from})
+			} else if to != from {
+				changes = append(changes, Change{Name: name, From: from, To: to})
+			}
+		}
+		for name, to := range after[module] {
+			if _, ok := before[module][name]; !ok {
+				changes = append(changes, Change{Name: name, To: to})
+			}
+		}
+		if len(changes) == 0 {
+			continue
+		}
+		slices.SortFunc(changes, func(a, b Change) int {
+			return cmp.Compare(a.Name, b.Name)
+		})
+		diff = append(diff, ModuleChanges{Module: module, Changes: changes})
+	}
+	slices.SortFunc(diff, func(a, b ModuleChanges) int {
+		return cmp.Compare(a.Module, b.Module)
+	})
+	return diff
+}
@@ -0,0 +1,118 @@
+package install
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		before Snapshot
+		after  Snapshot
+		want   []ModuleChanges
+	}{
+		{
+			name:   "unchanged",
+			before: Snapshot{".": {"a": "1.0.0"}},
+			after:  Snapshot{".": {"a": "1.0.0"}},
+			want:   nil,
+		},
+		{
+			name:   "added",
+			before: Snapshot{},
+			after:  Snapshot{".": {"b": "2.0.0", "a": "1.0.0"}},
+			want: []ModuleChanges{{Module: ".", Changes: []Change{
+				{Name: "a", To: "1.0.0"},
+				{Name: "b", To: "2.0.0"},
+			}}},
+		},
+		{
+			name:   "removed",
+			before: Snapshot{".": {"a": "1.0.0", "b": "2.0.0"}},
+			after:  Snapshot{".": {"b": "2.0.0"}},
+			want:   []ModuleChanges{{Module: ".", Changes: []Change{{Name: "a", From: "1.0.0"}}}},
+		},
+		{
+			name:   "changed",
+			before: Snapshot{".": {"a": "1.0.0"}},
+			after:  Snapshot{".": {"a": "1.1.0"}},
+			want:   []ModuleChanges{{Module: ".", Changes: []Change{{Name: "a", From: "1.0.0", To: "1.1.0"}}}},
+		},
+		{
+			name: "modules",
+			before: Snapshot{
+				".":            {"a": "1.0.0"},
+				"packages/web": {"b": "1.0.0"},
+				"packages/old": {"c": "1.0.0"},
+			},
+			after: Snapshot{
+				".":            {"a": "1.0.0"},
+				"packages/web": {"b": "2.0.0", "d": "1.0.0"},
+				"packages/new": {"e": "1.0.0"},
+			},
+			want: []ModuleChanges{
+				{Module: "packages/new", Changes: []Change{{Name: "e", To: "1.0.0"}}},
+				{Module: "packages/old", Changes: []Change{{Name: "c", From: "1.0.0"}}},
+				{Module: "packages/web", Changes: []Change{
+					{Name: "b", From: "1.0.0", To: "2.0.0"},
+					{Name: "d", To: "1.0.0"},
+				}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diff(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	root := t.TempDir()
+	writeFile := func(rel, content string) {
+		t.Helper()
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("package.json", `{"name":"app","dependencies":{"a":"^1.0.0","local":"file:./local"},"devDependencies":{"b":"^2.0.0"}}`)
+	writeFile("node_modules/a/package.json", `{"name":"a","version":"1.2.3"}`)
+	writeFile("node_modules/b/package.json", `{"name":"b","version":"2.0.0"}`)
+	writeFile("node_modules/local/package.json", `{"name":"local"}`)
+
+	empty, err := ReadSnapshot(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(empty) != 0 {
+		t.Errorf("expected an empty snapshot before the first install, got %v", empty)
+	}
+
+	snapshot := TakeSnapshot(root)
+	want := Snapshot{".": {"a": "1.2.3", "b": "2.0.0", "local": "file:./local"}}
+	if !reflect.DeepEqual(snapshot, want) {
+		t.Fatalf("TakeSnapshot() = %v, want %v", snapshot, want)
+	}
+	if err := WriteSnapshot(root, snapshot); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadSnapshot(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, snapshot) {
+		t.Errorf("ReadSnapshot() = %v, want %v", got, snapshot)
+	}
+	if diff := Diff(got, TakeSnapshot(root)); diff != nil {
+		t.Errorf("expected no changes after the round trip, got %+v", diff)
+	}
+}
@@ -14,6 +14,15 @@ func Printf(format string, args ...any) {
 	}
 }
 
+// Infof logs regardless of Verbose.
+func Infof(format string, args ...any) {
+	statusui.Log(fmt.Sprintf(format, args...), statusui.LogLevelInfo)
+}
+
+func Warnf(format string, args ...any) {
+	statusui.Log(fmt.Sprintf(format, args...), statusui.LogLevelWarn)
+}
+
 func Errorf(format string, args ...any) {
 	statusui.Log(fmt.Sprintf(format, args...), statusui.LogLevelError)
 }
@@ -0,0 +1,314 @@
+package patch
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type fileDiff struct {
+	oldPath string
+	newPath string
+	// perm is the permission of the new file, zero keeps the existing one
+	perm  os.FileMode
+	hunks []hunk
+}
+
+type hunk struct {
+	oldStart int
+	oldLines []line
+	newLines []line
+}
+
+// Apply applies a unified diff as created by DiffDirs to the files in dir.
+func Apply(dir string, diff []byte) error {
+	files, err := parse(diff)
+	if err != nil {
+		return fmt.Errorf("parse patch: %w", err)
+	}
+	for _, f := range files {
+		if err := applyFile(dir, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyFile(dir string, f fileDiff) error {
+	name := f.newPath
+	if name == "" {
+		name = f.oldPath
+	}
+	target, err := secureJoin(dir, name)
+	if err != nil {
+		return err
+	}
+
+	var lines []line
+	if f.oldPath != "" {
+		content, err := os.ReadFile(target)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", name, err)
+		}
+		lines = splitLines(content)
+	}
+
+	// offset tracks how far previous hunks moved the following lines
+	offset := 0
+	for _, h := range f.hunks {
+		pos, ok := findHunk(lines, h.oldLines, h.oldStart+offset)
+		if !ok {
+			return fmt.Errorf("%s: hunk at line %d does not apply", name, h.oldStart+1)
+		}
+		updated := make([]line, 0, len(lines)-len(h.oldLines)+len(h.newLines))
+		updated = append(updated, lines[:pos]...)
+		updated = append(updated, h.newLines...)
+		updated = append(updated, lines[pos+len(h.oldLines):]...)
+		lines = updated
+		offset = pos - h.oldStart + len(h.newLines) - len(h.oldLines)
+	}
+
+	if f.newPath == "" {
+		if err := os.Remove(target); err != nil {
+			return fmt.Errorf("remove %s: %w", name, err)
+		}
+		return nil
+	}
+
+	var out bytes.Buffer
+	for _, l := range lines {
+		out.WriteString(l.text)
+		if !l.noNewline {
+			out.WriteByte('\n')
+		}
+	}
+	perm := os.FileMode(0o644)
+	if stat, err := os.Stat(target); err == nil {
+		perm = stat.Mode().Perm()
+	}
+	if f.perm != 0 {
+		perm = f.perm
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("mkdir %s: %w", filepath.Dir(name), err)
+	}
+	// remove first so hardlinked files are not modified in place
+	_ = os.Remove(target)
+	if err := os.WriteFile(target, out.Bytes(), perm); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	return nil
+}
+
+// findHunk returns the position of want in lines, searching outwards from the expected position.
+func findHunk(lines, want []line, expected int) (int, bool) {
+	matchesAt := func(pos int) bool {
+		if pos < 0 || pos+len(want) > len(lines) {
+			return false
+		}
+		for i, l := range want {
+			if lines[pos+i] != l {
+				return false
+			}
+		}
+		return true
+	}
+	for delta := 0; delta <= len(lines); delta++ {
+		if matchesAt(expected - delta) {
+			return expected - delta, true
+		}
+		if delta != 0 && matchesAt(expected+delta) {
+			return expected + delta, true
+		}
+	}
+	return 0, false
+}
+
+func parse(diff []byte) ([]fileDiff, error) {
+	var files []fileDiff
+	var current *fileDiff
+	var currentHunk *hunk
+	// remaining lines of the current hunk
+	oldLeft, newLeft := 0, 0
+	// lastSide remembers which side the previous hunk line belonged to for "\ No newline" markers
+	var lastSide []*[]line
+
+	scanner := bufio.NewScanner(bytes.NewReader(diff))
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		text := scanner.Text()
+
+		if currentHunk != nil && (oldLeft > 0 || newLeft > 0) {
+			if text == "" {
+				// some editors strip the trailing space of empty context lines
+				text = " "
+			}
+			switch text[0] {
+			case ' ':
+				l := line{text: text[1:]}
+				currentHunk.oldLines = append(currentHunk.oldLines, l)
+				currentHunk.newLines = append(currentHunk.newLines, l)
+				lastSide = []*[]line{&currentHunk.oldLines, &currentHunk.newLines}
+				oldLeft--
+				newLeft--
+			case '-':
+				currentHunk.oldLines = append(currentHunk.oldLines, line{text: text[1:]})
+				lastSide = []*[]line{&currentHunk.oldLines}
+				oldLeft--
+			case '+':
+				currentHunk.newLines = append(currentHunk.newLines, line{text: text[1:]})
+				lastSide = []*[]line{&currentHunk.newLines}
+				newLeft--
+			case '\\':
+				markNoNewline(lastSide)
+			default:
+				return nil, fmt.Errorf("line %d: unexpected hunk line %q", lineNo, text)
+			}
+			if oldLeft < 0 || newLeft < 0 {
+				return nil, fmt.Errorf("line %d: hunk longer than its header", lineNo)
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(text, "\\"):
+			markNoNewline(lastSide)
+		case strings.HasPrefix(text, "diff --git "):
+			files = append(files, fileDiff{})
+			current = &files[len(files)-1]
+			currentHunk = nil
+			// mode changes have no --- and +++ lines, take the paths from the header
+			if a, b, ok := strings.Cut(strings.TrimPrefix(text, "diff --git "), " b/"); ok {
+				current.oldPath = diffPath(a)
+				current.newPath = b
+			}
+		case strings.HasPrefix(text, "new file mode "), strings.HasPrefix(text, "new mode "):
+			if current == nil {
+				return nil, fmt.Errorf("line %d: mode without file header", lineNo)
+			}
+			perm, err := parseMode(text[strings.LastIndexByte(text, ' ')+1:])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			current.perm = perm
+		case strings.HasPrefix(text, "--- "):
+			if current == nil || len(current.hunks) > 0 {
+				files = append(files, fileDiff{})
+				current = &files[len(files)-1]
+			}
+			current.oldPath = diffPath(strings.TrimPrefix(text, "--- "))
+		case strings.HasPrefix(text, "+++ "):
+			if current == nil {
+				return nil, fmt.Errorf("line %d: +++ without ---", lineNo)
+			}
+			current.newPath = diffPath(strings.TrimPrefix(text, "+++ "))
+		case strings.HasPrefix(text, "@@ "):
+			if current == nil {
+				return nil, fmt.Errorf("line %d: hunk without file header", lineNo)
+			}
+			h, oldCount, newCount, err := parseHunkHeader(text)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			current.hunks = append(current.hunks, h)
+			currentHunk = &current.hunks[len(current.hunks)-1]
+			oldLeft, newLeft = oldCount, newCount
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if oldLeft > 0 || newLeft > 0 {
+		return nil, errors.New("unexpected end of patch")
+	}
+	return files, nil
+}
+
+// parseMode converts a git file mode like 100755 to the permission of the file.
+func parseMode(mode string) (os.FileMode, error) {
+	m, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid file mode %q", mode)
+	}
+	if m&0o111 != 0 {
+		return 0o755, nil
+	}
+	return 0o644, nil
+}
+
+func markNoNewline(sides []*[]line) {
+	for _, side := range sides {
+		if n := len(*side); n > 0 {
+			(*side)[n-1].noNewline = true
+		}
+	}
+}
+
+// diffPath strips the a/ or b/ prefix and returns "" for /dev/null.
+func diffPath(p string) string {
+	if i := strings.IndexByte(p, '\t'); i >= 0 {
+		p = p[:i]
+	}
+	if p == "/dev/null" {
+		return ""
+	}
+	if rest, ok := strings.CutPrefix(p, "a/"); ok {
+		return rest
+	}
+	if rest, ok := strings.CutPrefix(p, "b/"); ok {
+		return rest
+	}
+	return p
+}
+
+func parseHunkHeader(text string) (h hunk, oldCount int, newCount int, err error) {
+	// @@ -l,s +l,s @@ optional section heading
+	fields := strings.Fields(text)
+	if len(fields) < 4 || fields[3] != "@@" && !strings.HasPrefix(fields[3], "@@") {
+		return hunk{}, 0, 0, fmt.Errorf("invalid hunk header %q", text)
+	}
+	oldStart, oldCount, err := parseRange(strings.TrimPrefix(fields[1], "-"))
+	if err != nil {
+		return hunk{}, 0, 0, err
+	}
+	_, newCount, err = parseRange(strings.TrimPrefix(fields[2], "+"))
+	if err != nil {
+		return hunk{}, 0, 0, err
+	}
+	// convert to a 0-based index; empty ranges already point at the line before
+	if oldCount > 0 {
+		oldStart--
+	}
+	return hunk{oldStart: oldStart}, oldCount, newCount, nil
+}
+
+func parseRange(s string) (start int, count int, err error) {
+	startStr, countStr, hasCount := strings.Cut(s, ",")
+	start, err = strconv.Atoi(startStr)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid hunk range %q", s)
+	}
+	count = 1
+	if hasCount {
+		count, err = strconv.Atoi(countStr)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid hunk range %q", s)
+		}
+	}
+	return start, count, nil
+}
+
+func secureJoin(base, name string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(name))
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path escapes package: %q", name)
+	}
+	return filepath.Join(base, clean), nil
+}
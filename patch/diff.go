@@ -0,0 +1,336 @@
+package patch
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// contextLines is the number of unchanged lines around each hunk
+const contextLines = 3
+
+// ignoredNames are skipped when comparing package directories
+var ignoredNames = map[string]struct{}{
+	"node_modules": {},
+	MetadataFile:   {},
+}
+
+// DiffDirs returns a unified diff that turns the files in oldDir into the files in newDir.
+// Paths in the diff are relative and use a/ and b/ prefixes like git.
+func DiffDirs(oldDir, newDir string) ([]byte, error) {
+	oldFiles, err := listFiles(oldDir)
+	if err != nil {
+		return nil, err
+	}
+	newFiles, err := listFiles(newDir)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(oldFiles)+len(newFiles))
+	for name := range oldFiles {
+		names = append(names, name)
+	}
+	for name := range newFiles {
+		if _, ok := oldFiles[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var out bytes.Buffer
+	for _, name := range names {
+		var oldContent, newContent []byte
+		_, inOld := oldFiles[name]
+		_, inNew := newFiles[name]
+		if inOld {
+			if oldContent, err = os.ReadFile(filepath.Join(oldDir, filepath.FromSlash(name))); err != nil {
+				return nil, err
+			}
+		}
+		if inNew {
+			if newContent, err = os.ReadFile(filepath.Join(newDir, filepath.FromSlash(name))); err != nil {
+				return nil, err
+			}
+		}
+		var oldMode, newMode string
+		if inOld {
+			if oldMode, err = gitMode(filepath.Join(oldDir, filepath.FromSlash(name))); err != nil {
+				return nil, err
+			}
+		}
+		if inNew {
+			if newMode, err = gitMode(filepath.Join(newDir, filepath.FromSlash(name))); err != nil {
+				return nil, err
+			}
+		}
+		contentChanged := !inOld || !inNew || !bytes.Equal(oldContent, newContent)
+		if !contentChanged && oldMode == newMode {
+			continue
+		}
+		if isBinary(oldContent) || isBinary(newContent) {
+			return nil, fmt.Errorf("binary file %s changed, only text files can be patched", name)
+		}
+
+		oldName, newName := "a/"+name, "b/"+name
+		if !inOld {
+			oldName = "/dev/null"
+		}
+		if !inNew {
+			newName = "/dev/null"
+		}
+		fmt.Fprintf(&out, "diff --git a/%s b/%s\n", name, name)
+		switch {
+		case !inOld:
+			fmt.Fprintf(&out, "new file mode %s\n", newMode)
+		case !inNew:
+			fmt.Fprintf(&out, "deleted file mode %s\n", oldMode)
+		case oldMode != newMode:
+			fmt.Fprintf(&out, "old mode %s\nnew mode %s\n", oldMode, newMode)
+		}
+		if !contentChanged {
+			continue
+		}
+		fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+		writeHunks(&out, splitLines(oldContent), splitLines(newContent))
+	}
+	return out.Bytes(), nil
+}
+
+func listFiles(root string) (map[string]struct{}, error) {
+	files := map[string]struct{}{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if _, ignored := ignoredNames[d.Name()]; ignored && path != root {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walk %s: %w", root, err)
+	}
+	return files, nil
+}
+
+// gitMode returns the mode git records for the file, 100755 for executables and 100644 otherwise.
+func gitMode(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.Mode().Perm()&0o111 != 0 {
+		return "100755", nil
+	}
+	return "100644", nil
+}
+
+func isBinary(content []byte) bool {
+	return bytes.IndexByte(content, 0) >= 0
+}
+
+// line is a single line of a file without its line break.
+// The last line of a file may lack the trailing newline.
+type line struct {
+	text      string
+	noNewline bool
+}
+
+func splitLines(content []byte) []line {
+	if len(content) == 0 {
+		return nil
+	}
+	s := string(content)
+	parts := strings.SplitAfter(s, "\n")
+	lines := make([]line, 0, len(parts))
+	for _, p := range parts {
+		if p == "" {
+			continue
+		}
+		if text, ok := strings.CutSuffix(p, "\n"); ok {
+			lines = append(lines, line{text: text})
+		} else {
+			lines = append(lines, line{text: p, noNewline: true})
+		}
+	}
+	return lines
+}
+
+type opKind uint8
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+type op struct {
+	kind opKind
+	// index into the old lines for equal/delete and into the new lines for insert
+	oldIndex int
+	newIndex int
+}
+
+// diffLines computes the shortest edit script using the Myers algorithm.
+func diffLines(a, b []line) []op {
+	n, m := len(a), len(b)
+	maxD := n + m
+	offset := maxD + 1
+	v := make([]int, 2*maxD+2)
+	var trace [][]int
+
+	for d := 0; d <= maxD; d++ {
+		snapshot := make([]int, len(v))
+		copy(snapshot, v)
+		trace = append(trace, snapshot)
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b, offset)
+			}
+		}
+	}
+	return nil
+}
+
+func backtrack(trace [][]int, a, b []line, offset int) []op {
+	x, y := len(a), len(b)
+	var ops []op
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, op{kind: opEqual, oldIndex: x, newIndex: y})
+		}
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, op{kind: opInsert, oldIndex: x, newIndex: prevY})
+			} else {
+				ops = append(ops, op{kind: opDelete, oldIndex: prevX, newIndex: y})
+			}
+		}
+		x, y = prevX, prevY
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+func writeHunks(out *bytes.Buffer, a, b []line) {
+	ops := diffLines(a, b)
+
+	i := 0
+	for i < len(ops) {
+		// find the next change
+		for i < len(ops) && ops[i].kind == opEqual {
+			i++
+		}
+		if i >= len(ops) {
+			return
+		}
+		start := max(i-contextLines, 0)
+		end := i
+		// extend the hunk while changes are close enough to share context
+		for end < len(ops) {
+			if ops[end].kind != opEqual {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == opEqual {
+				run++
+			}
+			if run == len(ops) || run-end > 2*contextLines {
+				end = min(end+contextLines, run)
+				break
+			}
+			end = run
+		}
+		writeHunk(out, ops[start:end], a, b)
+		i = end
+	}
+}
+
+func writeHunk(out *bytes.Buffer, ops []op, a, b []line) {
+	oldStart, newStart := ops[0].oldIndex, ops[0].newIndex
+	oldCount, newCount := 0, 0
+	for _, o := range ops {
+		switch o.kind {
+		case opEqual:
+			oldCount++
+			newCount++
+		case opDelete:
+			oldCount++
+		case opInsert:
+			newCount++
+		}
+	}
+	fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+	for _, o := range ops {
+		var prefix byte
+		var l line
+		switch o.kind {
+		case opEqual:
+			prefix, l = ' ', a[o.oldIndex]
+		case opDelete:
+			prefix, l = '-', a[o.oldIndex]
+		case opInsert:
+			prefix, l = '+', b[o.newIndex]
+		}
+		out.WriteByte(prefix)
+		out.WriteString(l.text)
+		out.WriteByte('\n')
+		if l.noNewline {
+			out.WriteString("\\ No newline at end of file\n")
+		}
+	}
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		// empty ranges point at the line before the change
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
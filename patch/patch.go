@@ -0,0 +1,163 @@
+package patch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// MetadataFile is written into directories created by `jmod patch`
+// to remember which package they are an editable copy of.
+const MetadataFile = ".jmod-patch.json"
+
+// Metadata describes an editable copy of a package.
+type Metadata struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// Original is the unmodified package the patch is created against
+	Original string `json:"original"`
+}
+
+func WriteMetadata(dir string, m Metadata) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, MetadataFile), data, 0o644)
+}
+
+func ReadMetadata(dir string) (Metadata, error) {
+	data, err := os.ReadFile(filepath.Join(dir, MetadataFile))
+	if err != nil {
+		return Metadata{}, fmt.Errorf("%s is not a directory created by jmod patch: %w", dir, err)
+	}
+	var m Metadata
+	if err := json.Unmarshal(data, &m); err != nil {
+		return Metadata{}, fmt.Errorf("decode %s: %w", MetadataFile, err)
+	}
+	return m, nil
+}
+
+// Hash returns a short content hash of a patch.
+func Hash(diff []byte) string {
+	sum := sha256.Sum256(diff)
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// FileName returns the file name used for the patch of a package, e.g. @scope+name@1.0.0.patch.
+func FileName(name, version string) string {
+	return fmt.Sprintf("%s@%s.patch", strings.ReplaceAll(name, "/", "+"), version)
+}
+
+// CopyDir copies the package at src to dst, skipping its node_modules.
+func CopyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			if d.Name() == "node_modules" && path != src {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(target, 0o755)
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			linkTarget, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(linkTarget, target)
+		case info.Mode().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		default:
+			return nil
+		}
+	})
+}
+
+func copyFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+var localCopyMu sync.Mutex
+
+// PatchedCopy creates a copy of the package at original with diff applied at dest.
+// An existing dest is reused, dest should therefore contain the Hash of the diff.
+// The original directory is never modified.
+func PatchedCopy(original string, diff []byte, dest string) error {
+	return localCopy(original, dest, func(dir string) error {
+		if err := Apply(dir, diff); err != nil {
+			return fmt.Errorf("apply patch: %w", err)
+		}
+		return nil
+	})
+}
+
+// LocalCopy creates a copy of the package at original at dest, without its node_modules.
+// An existing dest is reused. The original directory is never modified.
+func LocalCopy(original string, dest string) error {
+	return localCopy(original, dest, nil)
+}
+
+func localCopy(original string, dest string, edit func(dir string) error) error {
+	localCopyMu.Lock()
+	defer localCopyMu.Unlock()
+
+	if _, err := os.Stat(dest); err == nil {
+		return nil
+	}
+
+	parent := filepath.Dir(dest)
+	if err := os.MkdirAll(parent, 0o755); err != nil {
+		return fmt.Errorf("mkdir parent: %w", err)
+	}
+	staging, err := os.MkdirTemp(parent, ".copy-*")
+	if err != nil {
+		return fmt.Errorf("mktemp staging: %w", err)
+	}
+	// Clean up staging on error; on success we rename it and cleanup is moot.
+	defer os.RemoveAll(staging)
+
+	if err := CopyDir(original, staging); err != nil {
+		return fmt.Errorf("copy %s: %w", original, err)
+	}
+	if edit != nil {
+		if err := edit(staging); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(staging, dest); err != nil {
+		return fmt.Errorf("rename staging: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package patch
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDiffApplyRoundTrip(t *testing.T) {
+	var long strings.Builder
+	for i := range 40 {
+		long.WriteString(strings.Repeat("x", i))
+		long.WriteByte('\n')
+	}
+	original := map[string]string{
+		"package.json":  "{\n  \"name\": \"example\"\n}\n",
+		"lib/index.js":  long.String(),
+		"lib/remove.js": "gone\n",
+		"no-newline.js": "a\nb",
+	}
+	modifiedLong := strings.Replace(long.String(), "xxx\n", "yyy\n", 1)
+	modifiedLong = strings.Replace(modifiedLong, strings.Repeat("x", 30)+"\n", "", 1)
+	modified := map[string]string{
+		"package.json":  "{\n  \"name\": \"example\"\n}\n",
+		"lib/index.js":  modifiedLong,
+		"lib/added.js":  "new file\n",
+		"no-newline.js": "a\nc\n",
+	}
+
+	oldDir, newDir := t.TempDir(), t.TempDir()
+	writeFiles(t, oldDir, original)
+	writeFiles(t, newDir, modified)
+
+	diff, err := DiffDirs(oldDir, newDir)
+	if err != nil {
+		t.Fatalf("DiffDirs: %v", err)
+	}
+	if strings.Contains(string(diff), "package.json") {
+		t.Errorf("unchanged file in diff:\n%s", diff)
+	}
+
+	target := filepath.Join(t.TempDir(), "patched")
+	if err := PatchedCopy(oldDir, diff, target); err != nil {
+		t.Fatalf("PatchedCopy: %v\n%s", err, diff)
+	}
+
+	for name, want := range modified {
+		got, err := os.ReadFile(filepath.Join(target, filepath.FromSlash(name)))
+		if err != nil {
+			t.Errorf("read %s: %v", name, err)
+			continue
+		}
+		if string(got) != want {
+			t.Errorf("%s: got %q, want %q", name, got, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(target, "lib", "remove.js")); !os.IsNotExist(err) {
+		t.Errorf("deleted file still exists: %v", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(oldDir, "lib", "index.js")); string(got) != long.String() {
+		t.Errorf("original was modified")
+	}
+}
+
+func TestDiffApplyFileModes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no executable bit on windows")
+	}
+	oldDir, newDir := t.TempDir(), t.TempDir()
+	writeFiles(t, oldDir, map[string]string{"bin/cli.js": "#!/usr/bin/env node\n"})
+	writeFiles(t, newDir, map[string]string{
+		"bin/cli.js":       "#!/usr/bin/env node\n",
+		"scripts/build.sh": "#!/bin/sh\necho build\n",
+	})
+	for _, name := range []string{"bin/cli.js", "scripts/build.sh"} {
+		if err := os.Chmod(filepath.Join(newDir, filepath.FromSlash(name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	diff, err := DiffDirs(oldDir, newDir)
+	if err != nil {
+		t.Fatalf("DiffDirs: %v", err)
+	}
+	for _, want := range []string{"new file mode 100755\n", "old mode 100644\nnew mode 100755\n"} {
+		if !strings.Contains(string(diff), want) {
+			t.Errorf("expected %q in diff:\n%s", want, diff)
+		}
+	}
+
+	target := filepath.Join(t.TempDir(), "patched")
+	if err := PatchedCopy(oldDir, diff, target); err != nil {
+		t.Fatalf("PatchedCopy: %v\n%s", err, diff)
+	}
+	for _, name := range []string{"bin/cli.js", "scripts/build.sh"} {
+		info, err := os.Stat(filepath.Join(target, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm()&0o111 == 0 {
+			t.Errorf("%s is not executable: %s", name, info.Mode())
+		}
+	}
+}
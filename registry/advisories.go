@@ -0,0 +1,65 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Advisory is a security advisory as returned by the npm bulk advisory endpoint.
+type Advisory struct {
+	Id                 int64    `json:"id"`
+	Url                string   `json:"url"`
+	Title              string   `json:"title"`
+	Severity           string   `json:"severity"`
+	VulnerableVersions string   `json:"vulnerable_versions"`
+	Cwe                []string `json:"cwe"`
+	Cvss               struct {
+		Score        float64 `json:"score"`
+		VectorString string  `json:"vectorString"`
+	} `json:"cvss"`
+}
+
+// Npm_BulkAdvisories posts the installed versions of each package to the
+// bulk advisory endpoint of NpmRegistry and returns the advisories per package name.
+// The registry might return advisories that do not affect the given versions.
+func Npm_BulkAdvisories(ctx context.Context, versions map[string][]string) (map[string][]Advisory, error) {
+	body, err := json.Marshal(versions)
+	if err != nil {
+		return nil, fmt.Errorf("encode request: %w", err)
+	}
+	url := strings.TrimSuffix(NpmRegistry, "/") + "/-/npm/v1/security/advisories/bulk"
+	resp, err := doWithRetry(ctx, httpClient, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("http post %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http post %s: bad status: %s", url, resp.Status)
+	}
+
+	var advisories map[string][]Advisory
+	if err := json.NewDecoder(resp.Body).Decode(&advisories); err != nil {
+		// some registries answer without a body if nothing is affected
+		if errors.Is(err, io.EOF) {
+			return map[string][]Advisory{}, nil
+		}
+		return nil, fmt.Errorf("decode npm API response %s: %w", url, err)
+	}
+	if advisories == nil {
+		advisories = map[string][]Advisory{}
+	}
+	return advisories, nil
+}
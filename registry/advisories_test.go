@@ -0,0 +1,62 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNpmBulkAdvisories(t *testing.T) {
+	defer func(registry string) { NpmRegistry = registry }(NpmRegistry)
+	defer func(d time.Duration) { FetchRetryMinDelay = d }(FetchRetryMinDelay)
+	FetchRetryMinDelay = time.Millisecond
+
+	var got map[string][]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/-/npm/v1/security/advisories/bulk" {
+			http.NotFound(w, r)
+			return
+		}
+		got = nil
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		switch {
+		case got["empty"] != nil:
+		case got["broken"] != nil:
+			http.Error(w, "nope", http.StatusForbidden)
+		default:
+			_, _ = w.Write([]byte(`{"minimist":[{"id":1179,"title":"Prototype Pollution","severity":"high","vulnerable_versions":"<1.2.6"}]}`))
+		}
+	}))
+	defer srv.Close()
+	NpmRegistry = srv.URL + "/"
+
+	versions := map[string][]string{"minimist": {"1.2.5", "1.2.8"}}
+	advisories, err := Npm_BulkAdvisories(context.Background(), versions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, versions) {
+		t.Errorf("posted %v, want %v", got, versions)
+	}
+	if a := advisories["minimist"]; len(a) != 1 || a[0].Id != 1179 || a[0].Severity != "high" || a[0].VulnerableVersions != "<1.2.6" {
+		t.Errorf("unexpected advisories %+v", advisories)
+	}
+
+	advisories, err = Npm_BulkAdvisories(context.Background(), map[string][]string{"empty": {"1.0.0"}})
+	if err != nil || len(advisories) != 0 {
+		t.Errorf("empty body: got %v, %v, want no advisories", advisories, err)
+	}
+
+	_, err = Npm_BulkAdvisories(context.Background(), map[string][]string{"broken": {"1.0.0"}})
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("expected the bad status to be reported, got %v", err)
+	}
+}
@@ -25,12 +25,19 @@ import (
 
 	"github.com/Masterminds/semver/v3"
 	"github.com/tsukinoko-kun/disize"
+	"github.com/tsukinoko-kun/jmod/logger"
 	"github.com/tsukinoko-kun/jmod/statusui"
 	"github.com/ulikunitz/xz"
 )
 
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
 var cacheLocation string
 var tarballCacheLocation string
+var nativeBuildCacheLocation string
+
+// cacheLocationMu guards the lazily created cache locations, packages are resolved concurrently
+var cacheLocationMu sync.Mutex
 
 var (
 	cacheLocks = map[string]*sync.Mutex{}
@@ -68,6 +75,8 @@ func lockCache(registry string, packageName string, version string) (unlock func
 }
 
 func getCacheLocation() string {
+	cacheLocationMu.Lock()
+	defer cacheLocationMu.Unlock()
 	if cacheLocation != "" {
 		return cacheLocation
 	}
@@ -112,6 +121,8 @@ func GetTarballCacheLocation() string {
 }
 
 func getTarballCacheLocation() string {
+	cacheLocationMu.Lock()
+	defer cacheLocationMu.Unlock()
 	if tarballCacheLocation != "" {
 		return tarballCacheLocation
 	}
@@ -136,7 +147,26 @@ func getTarballCacheLocation() string {
 	return tarballCacheLocation
 }
 
-func CacheHas(registry string, packageName string, versionConstrains *semver.Constraints) (bool, string) {
+// GetNativeBuildCacheLocation returns the directory where native addon builds are cached.
+func GetNativeBuildCacheLocation() string {
+	cacheLocationMu.Lock()
+	defer cacheLocationMu.Unlock()
+	if nativeBuildCacheLocation != "" {
+		return nativeBuildCacheLocation
+	}
+
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		panic(err)
+	}
+	nativeBuildCacheLocation = filepath.Join(userCacheDir, "jmod-native")
+	if err := os.MkdirAll(nativeBuildCacheLocation, 0755); err != nil {
+		panic(err)
+	}
+	return nativeBuildCacheLocation
+}
+
+func CacheHas(ctx context.Context, registry string, packageName string, versionConstrains *semver.Constraints) (bool, string) {
 	if versionConstrains == nil {
 		return false, ""
 	}
@@ -159,7 +189,9 @@ func CacheHas(registry string, packageName string, versionConstrains *semver.Con
 				continue
 			}
 			var manifest struct {
-				Name string `json:"name"`
+				Name    string  `json:"name"`
+				Engines Engines `json:"engines"`
+				PlatformRequirements
 			}
 			if err := json.Unmarshal(data, &manifest); err != nil {
 				_ = os.RemoveAll(pkgDir)
@@ -169,6 +201,18 @@ func CacheHas(registry string, packageName string, versionConstrains *semver.Con
 				_ = os.RemoveAll(pkgDir)
 				continue
 			}
+			// the cache is shared between cross-installs, keep entries for other platforms
+			if !manifest.Supports(TargetPlatform) {
+				continue
+			}
+			// let Npm_Resolve pick a compatible version or report the failure
+			if (EngineStrict || PreferCompatibleEngines) && len(manifest.Engines.Unsatisfied()) != 0 {
+				continue
+			}
+			if !verifyCached(ctx, registry, packageName, entry.Name(), pkgDir) {
+				continue
+			}
+			_ = checkEngines(fmt.Sprintf("%s:%s@%s", registry, packageName, entry.Name()), manifest.Engines)
 			return true, filepath.Join(pkgDir, "package")
 		}
 	}
@@ -194,6 +238,8 @@ func CachePut(ctx context.Context, registry string, r Resolveable) (string, erro
 		return filepath.Join(packageLocation, "package"), nil
 	}
 
+	defer fetchTimer.Start()()
+
 	// Prepare parent directory for temp artifacts and final extraction.
 	parent := filepath.Dir(packageLocation)
 	if err := os.MkdirAll(parent, 0o755); err != nil {
@@ -246,7 +292,7 @@ func CachePut(ctx context.Context, registry string, r Resolveable) (string, erro
 		return "", err
 	}
 	if subtle.ConstantTimeCompare(expSum, gotSum) != 1 {
-		err := fmt.Errorf("checksum mismatch for %s %s", r.GetName(), r.GetVersion())
+		err := fmt.Errorf("%w for %s %s", ErrChecksumMismatch, r.GetName(), r.GetVersion())
 		statusui.Set(statusKey, statusui.ErrorStatus{
 			Message: fmt.Sprintf("Checksum mismatch for %s@%s", r.GetName(), r.GetVersion()),
 			Err:     err,
@@ -284,6 +330,12 @@ func CachePut(ctx context.Context, registry string, r Resolveable) (string, erro
 		return "", err
 	}
 
+	if v, ok := r.(interface{ signatureVerified() bool }); ok && v.signatureVerified() {
+		if err := os.WriteFile(filepath.Join(staging, signatureMarker), nil, 0o644); err != nil {
+			logger.Printf("failed to mark %s@%s as verified: %s", r.GetName(), r.GetVersion(), err)
+		}
+	}
+
 	// Replace existing destination atomically.
 	if err := os.RemoveAll(packageLocation); err != nil {
 		statusui.Set(statusKey, statusui.ErrorStatus{
@@ -361,79 +413,152 @@ func downloadToTempWithChecksum(
 		// If cache verification failed, continue to download
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	pkg := fmt.Sprintf("%s@%s", packageName, packageVersion)
+
+	// The temp file name is derived from the URL so a download interrupted
+	// by a failed attempt or a previous install is resumed instead of restarted.
+	tmpPath = filepath.Join(destDir, fmt.Sprintf(".download-%x.partial", sha256.Sum256([]byte(url))))
+	for attempt := 0; ; attempt++ {
+		resp, err := downloadPartial(ctx, url, tmpPath, statusKey, pkg)
+		if err == nil {
+			break
+		}
+		retryable := retryableError(err) || errors.Is(err, errRestartDownload)
+		if resp != nil {
+			retryable = retryableStatus(resp.StatusCode)
+			if !retryable {
+				// the server refused the download, a partial file of it is of no use anymore
+				_ = os.Remove(tmpPath)
+			}
+		}
+		if attempt >= FetchRetries || !retryable {
+			return "", nil, err
+		}
+		delay := retryDelay(attempt, resp)
+		logger.Printf("download %s@%s failed (%s), retrying in %s", packageName, packageVersion, err, delay)
+		statusui.Set(statusKey, statusui.PendingStatus{
+			Text: fmt.Sprintf("⏳ Retrying %s@%s in %s", packageName, packageVersion, delay.Round(time.Second)),
+		})
+		if err := sleepCtx(ctx, delay); err != nil {
+			return "", nil, err
+		}
+	}
+
+	f, err := os.Open(tmpPath)
 	if err != nil {
-		return "", nil, fmt.Errorf("new request: %w", err)
+		return "", nil, fmt.Errorf("open download: %w", err)
+	}
+	defer f.Close()
+	h.Reset()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", nil, fmt.Errorf("hash download: %w", err)
 	}
-	// Separate client gives us body read deadline via context.
-	client := &http.Client{Timeout: 0}
+	sum = h.Sum(nil)
+	if len(sum) != digestSize {
+		return "", nil, fmt.Errorf("unexpected digest size")
+	}
+
+	// Save to tarball cache for future use
+	saveTarballToCache(url, tmpPath, sum, cf)
+	recordDownloaded(packageName, packageVersion)
+
+	return tmpPath, sum, nil
+}
 
-	resp, err := client.Do(req)
+// errRestartDownload is returned by downloadPartial when the partial file was removed
+// and the download has to start over.
+var errRestartDownload = errors.New("restarting")
+
+// downloadPartial downloads url to path, continuing an existing partial file with an HTTP Range request.
+// The partial file is kept on errors so the next attempt can resume it.
+// The response is returned for bad statuses so the caller can decide whether to retry.
+func downloadPartial(ctx context.Context, url string, path string, statusKey string, pkg string) (*http.Response, error) {
+	var offset int64
+	if info, err := os.Stat(path); err == nil {
+		offset = info.Size()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return "", nil, fmt.Errorf("http get: %w", err)
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	// the request waits for a free slot of the host before it is sent
+	statusui.Set(statusKey, statusui.PendingStatus{
+		Text: fmt.Sprintf("🕓 Queued %s", pkg),
+	})
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http get: %w", err)
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return "", nil, fmt.Errorf("bad status: %s", resp.Status)
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0 && strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", offset)):
+		flags |= os.O_APPEND
+		logger.Printf("resuming download of %s at %s", url, disize.Size(offset).String())
+	case resp.StatusCode == http.StatusOK:
+		// the server ignored the range, start over
+		offset = 0
+		flags |= os.O_TRUNC
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		_ = os.Remove(path)
+		return nil, fmt.Errorf("partial download is stale: %w", errRestartDownload)
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		// the server sent another range than requested, the partial file cannot be continued
+		_ = os.Remove(path)
+		return nil, fmt.Errorf("partial download got range %q instead of offset %d: %w", resp.Header.Get("Content-Range"), offset, errRestartDownload)
+	default:
+		return resp, fmt.Errorf("bad status: %s", resp.Status)
 	}
 
-	f, err := os.CreateTemp(destDir, ".download-*.tmp")
+	f, err := os.OpenFile(path, flags, 0o644)
 	if err != nil {
-		return "", nil, fmt.Errorf("create temp: %w", err)
+		return nil, fmt.Errorf("open temp: %w", err)
 	}
-	tmpPath = f.Name()
-
-	// On error, ensure the temp file is removed by caller's defer.
-	defer func() {
-		f.Close()
-	}()
+	defer f.Close()
 
-	// Get content length for progress tracking
-	contentLength := resp.ContentLength
+	label := "⬇️  Downloading " + pkg
 
 	// Create a progress reader
 	var reader io.Reader = resp.Body
-	if contentLength > 0 {
+	if resp.ContentLength > 0 {
+		total := offset + resp.ContentLength
 		statusui.Set(statusKey, statusui.ProgressStatus{
-			Label:   fmt.Sprintf("⬇️  Downloading %s@%s", packageName, packageVersion),
-			Current: 0,
-			Total:   contentLength,
+			Label:   label,
+			Current: offset,
+			Total:   total,
 		})
 		reader = &progressReader{
 			reader:    resp.Body,
 			statusKey: statusKey,
-			label:     fmt.Sprintf("⬇️  Downloading %s@%s", packageName, packageVersion),
-			total:     contentLength,
+			label:     label,
+			total:     total,
+			current:   offset,
+			lastPrint: offset,
 		}
 	} else {
 		statusui.Set(statusKey, statusui.TextStatus{
-			Text: fmt.Sprintf("⬇️  Downloading %s@%s", packageName, packageVersion),
+			Text: label,
 		})
 	}
 
-	// Stream copy into file and hasher simultaneously.
-	_, err = io.Copy(io.MultiWriter(f, h), reader)
+	n, err := io.Copy(f, reader)
+	statsBytes.Add(n)
 	if err != nil {
-		return "", nil, fmt.Errorf("copy: %w", err)
+		return nil, fmt.Errorf("copy: %w", err)
 	}
-
 	// Flush to disk before verification.
 	if err := f.Sync(); err != nil {
-		return "", nil, fmt.Errorf("fsync: %w", err)
+		return nil, fmt.Errorf("fsync: %w", err)
 	}
 	if err := f.Close(); err != nil {
-		return "", nil, fmt.Errorf("close: %w", err)
-	}
-
-	sum = h.Sum(nil)
-	if len(sum) != digestSize {
-		return "", nil, fmt.Errorf("unexpected digest size")
+		return nil, fmt.Errorf("close: %w", err)
 	}
-
-	// Save to tarball cache for future use
-	saveTarballToCache(url, tmpPath, sum, cf)
-
-	return tmpPath, sum, nil
+	return nil, nil
 }
 
 // progressReader wraps an io.Reader to track download progress
@@ -0,0 +1,110 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/tsukinoko-kun/jmod/logger"
+)
+
+var ErrUnsupportedEngine = errors.New("unsupported engine")
+
+var (
+	// EngineVersions holds the detected runtime versions packages are checked against,
+	// keyed by engine name as used in the package.json engines field (node, npm, jmod).
+	// Engines without a detected version are not checked.
+	EngineVersions = map[string]*semver.Version{}
+	// EngineDetectors detect the version of engines missing in EngineVersions
+	// when a package constrains them, so slow detections only run if needed.
+	// They are called concurrently and must cache their result, nil means not detected.
+	EngineDetectors = map[string]func() *semver.Version{}
+	// EngineStrict makes packages with unsatisfied engines fail instead of producing a warning.
+	EngineStrict bool
+	// PreferCompatibleEngines makes range resolution pick the highest version whose engines are satisfied.
+	PreferCompatibleEngines bool
+)
+
+var (
+	engineWarningsMu sync.Mutex
+	engineWarnings   = map[string]struct{}{}
+)
+
+// Engines decodes the engines field of a package manifest.
+// Legacy array forms and non-string values are ignored.
+type Engines map[string]string
+
+func (e *Engines) UnmarshalJSON(data []byte) error {
+	*e = nil
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || data[0] != '{' {
+		return nil
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil
+	}
+	engines := make(Engines, len(raw))
+	for name, value := range raw {
+		if s, ok := value.(string); ok {
+			engines[name] = s
+		}
+	}
+	*e = engines
+	return nil
+}
+
+// Unsatisfied returns the engines whose constraint does not match the detected runtime version.
+// Invalid constraints and engines without a detected version are treated as satisfied.
+func (e Engines) Unsatisfied() []string {
+	var unsatisfied []string
+	for name, constraint := range e {
+		version := engineVersion(name)
+		if version == nil {
+			continue
+		}
+		c, err := semver.NewConstraint(constraint)
+		if err != nil {
+			continue
+		}
+		if !c.Check(version) {
+			unsatisfied = append(unsatisfied, fmt.Sprintf("%s %s (found %s)", name, constraint, version.String()))
+		}
+	}
+	sort.Strings(unsatisfied)
+	return unsatisfied
+}
+
+func engineVersion(name string) *semver.Version {
+	if version, ok := EngineVersions[name]; ok {
+		return version
+	}
+	if detect, ok := EngineDetectors[name]; ok {
+		return detect()
+	}
+	return nil
+}
+
+// checkEngines fails for packages with unsatisfied engines when EngineStrict is set and warns once per package otherwise.
+func checkEngines(pkg string, engines Engines) error {
+	unsatisfied := engines.Unsatisfied()
+	if len(unsatisfied) == 0 {
+		return nil
+	}
+	if EngineStrict {
+		return fmt.Errorf("%w: %s requires %s", ErrUnsupportedEngine, pkg, strings.Join(unsatisfied, ", "))
+	}
+	engineWarningsMu.Lock()
+	_, warned := engineWarnings[pkg]
+	engineWarnings[pkg] = struct{}{}
+	engineWarningsMu.Unlock()
+	if !warned {
+		logger.Warnf("unsupported engine: %s requires %s", pkg, strings.Join(unsatisfied, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+func TestEnginesUnsatisfied(t *testing.T) {
+	EngineVersions = map[string]*semver.Version{
+		"node": semver.MustParse("18.19.0"),
+	}
+	defer func() { EngineVersions = map[string]*semver.Version{} }()
+
+	tests := []struct {
+		name     string
+		manifest string
+		want     int
+	}{
+		{"satisfied", `{"engines":{"node":">=16"}}`, 0},
+		{"unsatisfied", `{"engines":{"node":">=20"}}`, 1},
+		{"or range", `{"engines":{"node":"^16 || ^18"}}`, 0},
+		{"unknown engine", `{"engines":{"vscode":"^1.80.0"}}`, 0},
+		{"invalid constraint", `{"engines":{"node":"latest please"}}`, 0},
+		{"legacy array", `{"engines":["node >= 0.4"]}`, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var manifest struct {
+				Engines Engines `json:"engines"`
+			}
+			if err := json.Unmarshal([]byte(tt.manifest), &manifest); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if got := manifest.Engines.Unsatisfied(); len(got) != tt.want {
+				t.Errorf("Unsatisfied() = %v, want %d entries", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckEnginesStrict(t *testing.T) {
+	EngineVersions = map[string]*semver.Version{
+		"node": semver.MustParse("18.19.0"),
+	}
+	EngineStrict = true
+	defer func() {
+		EngineVersions = map[string]*semver.Version{}
+		EngineStrict = false
+	}()
+
+	err := checkEngines("npm:example@1.0.0", Engines{"node": ">=20"})
+	if !errors.Is(err, ErrUnsupportedEngine) {
+		t.Errorf("expected ErrUnsupportedEngine, got %v", err)
+	}
+}
+
+func TestEngineDetectorsAreLazy(t *testing.T) {
+	calls := 0
+	EngineDetectors = map[string]func() *semver.Version{
+		"npm": func() *semver.Version {
+			calls++
+			return semver.MustParse("9.0.0")
+		},
+	}
+	defer func() { EngineDetectors = map[string]func() *semver.Version{} }()
+
+	if unsatisfied := (Engines{"node": ">=20"}).Unsatisfied(); len(unsatisfied) != 0 || calls != 0 {
+		t.Fatalf("npm detected without npm constraint: %v, %d calls", unsatisfied, calls)
+	}
+	if unsatisfied := (Engines{"npm": ">=10"}).Unsatisfied(); len(unsatisfied) != 1 || calls != 1 {
+		t.Errorf("expected unsatisfied npm constraint after one detection, got %v, %d calls", unsatisfied, calls)
+	}
+}
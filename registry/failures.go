@@ -0,0 +1,94 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/tsukinoko-kun/jmod/meta"
+)
+
+type FailureKind uint8
+
+const (
+	FailureResolution FailureKind = iota
+	FailureDownload
+	FailureChecksum
+	FailurePatch
+	FailureLink
+	FailureScript
+)
+
+func (k FailureKind) String() string {
+	switch k {
+	case FailureResolution:
+		return "resolution"
+	case FailureDownload:
+		return "download"
+	case FailureChecksum:
+		return "checksum mismatch"
+	case FailurePatch:
+		return "patch"
+	case FailureLink:
+		return "link"
+	case FailureScript:
+		return "script"
+	default:
+		return "unknown"
+	}
+}
+
+// Failure is the failure of a single package during an install.
+type Failure struct {
+	Kind    FailureKind
+	Package string
+	Chain   DependencyChain
+	Err     error
+}
+
+func (f *Failure) Error() string {
+	if len(f.Chain) == 0 {
+		return f.Err.Error()
+	}
+	return fmt.Sprintf("%s: %s", f.Chain.String(), f.Err)
+}
+
+func (f *Failure) Unwrap() error {
+	return f.Err
+}
+
+// KeepGoing makes failures of single packages not cancel the install,
+// so everything not depending on them is still installed.
+var KeepGoing bool
+
+var (
+	failuresMu sync.Mutex
+	failures   []*Failure
+)
+
+// Fail records the failure of a required package.
+// Without KeepGoing the install is canceled.
+func Fail(kind FailureKind, pkg string, chain DependencyChain, err error) {
+	f := &Failure{Kind: kind, Package: pkg, Chain: chain, Err: err}
+	failuresMu.Lock()
+	failures = append(failures, f)
+	failuresMu.Unlock()
+	if !KeepGoing {
+		meta.CancelCause(f)
+	}
+}
+
+// Failures returns the failures recorded so far.
+func Failures() []*Failure {
+	failuresMu.Lock()
+	defer failuresMu.Unlock()
+	return append([]*Failure(nil), failures...)
+}
+
+// DownloadFailureKind classifies an error returned by CachePut.
+func DownloadFailureKind(err error) FailureKind {
+	if errors.Is(err, ErrChecksumMismatch) {
+		return FailureChecksum
+	}
+	return FailureDownload
+}
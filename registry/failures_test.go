@@ -0,0 +1,48 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/tsukinoko-kun/jmod/meta"
+)
+
+func TestFail(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	meta.CancelCause = cancel
+	defer func() {
+		KeepGoing = false
+		failures = nil
+	}()
+
+	KeepGoing = true
+	checksumErr := fmt.Errorf("failed to cache a@1.0.0: %w", ErrChecksumMismatch)
+	Fail(DownloadFailureKind(checksumErr), "a", DependencyChain{"package.json", "b@2.0.0"}, checksumErr)
+	if ctx.Err() != nil {
+		t.Fatal("KeepGoing must not cancel the install")
+	}
+
+	KeepGoing = false
+	Fail(FailureLink, "c", nil, errors.New("link failed"))
+	if ctx.Err() == nil {
+		t.Fatal("expected the install to be canceled")
+	}
+
+	got := Failures()
+	if len(got) != 2 {
+		t.Fatalf("expected 2 failures, got %d", len(got))
+	}
+	if got[0].Kind != FailureChecksum || !errors.Is(got[0], ErrChecksumMismatch) {
+		t.Errorf("unexpected first failure %#v", got[0])
+	}
+	if want := "package.json -> b@2.0.0: failed to cache a@1.0.0: checksum mismatch"; got[0].Error() != want {
+		t.Errorf("got %q, want %q", got[0].Error(), want)
+	}
+	var f *Failure
+	if !errors.As(context.Cause(ctx), &f) || f.Package != "c" {
+		t.Errorf("unexpected cancel cause %v", context.Cause(ctx))
+	}
+}
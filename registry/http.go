@@ -0,0 +1,128 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tsukinoko-kun/jmod/statusui"
+)
+
+// MaxRequestsPerHost limits the concurrent requests to a single host.
+// Requests above the limit wait in a queue.
+var MaxRequestsPerHost = 16
+
+// httpTransport is shared by every registry and tarball request so connections are reused.
+var httpTransport = &http.Transport{
+	Proxy: http.ProxyFromEnvironment,
+	DialContext: (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext,
+	ForceAttemptHTTP2:     true,
+	MaxIdleConns:          256,
+	MaxIdleConnsPerHost:   64,
+	IdleConnTimeout:       90 * time.Second,
+	TLSHandshakeTimeout:   10 * time.Second,
+	ExpectContinueTimeout: time.Second,
+}
+
+// httpClient has no timeout, request lifetimes are bounded by their context.
+var httpClient = &http.Client{
+	Transport: &hostLimiter{base: httpTransport},
+}
+
+const networkStatusKey = "network"
+
+// hostLimiter bounds the concurrent requests per host.
+// A request holds its slot until the response body is closed.
+type hostLimiter struct {
+	base http.RoundTripper
+
+	mu     sync.Mutex
+	hosts  map[string]chan struct{}
+	queued int
+	active int
+}
+
+func (l *hostLimiter) RoundTrip(req *http.Request) (*http.Response, error) {
+	release, err := l.acquire(req.Context(), req.URL.Host)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := l.base.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+func (l *hostLimiter) acquire(ctx context.Context, host string) (release func(), err error) {
+	l.mu.Lock()
+	if l.hosts == nil {
+		l.hosts = make(map[string]chan struct{})
+	}
+	slots, ok := l.hosts[host]
+	if !ok {
+		slots = make(chan struct{}, max(MaxRequestsPerHost, 1))
+		l.hosts[host] = slots
+	}
+	l.queued++
+	l.report()
+	l.mu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.queued--
+		l.report()
+		l.mu.Unlock()
+		return nil, context.Cause(ctx)
+	}
+
+	l.mu.Lock()
+	l.queued--
+	l.active++
+	l.report()
+	l.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			<-slots
+			l.mu.Lock()
+			l.active--
+			l.report()
+			l.mu.Unlock()
+		})
+	}, nil
+}
+
+// report shows the request counts in the status UI, l.mu must be held.
+func (l *hostLimiter) report() {
+	if l.queued == 0 && l.active == 0 {
+		statusui.Clear(networkStatusKey)
+		return
+	}
+	statusui.Set(networkStatusKey, statusui.GaugeStatus{
+		Text: fmt.Sprintf("🌐 %d active, %d queued requests", l.active, l.queued),
+	})
+}
+
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
@@ -0,0 +1,127 @@
+package registry
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// NetworkConfig holds the proxy and TLS options of every registry and tarball request.
+// Empty proxy fields fall back to the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+type NetworkConfig struct {
+	// Proxy is used for http requests and for https requests if HTTPSProxy is empty.
+	Proxy string
+	// HTTPSProxy is used for https requests.
+	HTTPSProxy string
+	// NoProxy is a comma separated list of hosts, domains and CIDRs that are requested directly.
+	NoProxy string
+	// CA holds PEM encoded certificates, they replace the system roots like npm's ca option.
+	CA []string
+	// CAFile is a PEM file that replaces the system roots.
+	CAFile string
+	// StrictSSL verifies the TLS certificates of the registry.
+	StrictSSL bool
+}
+
+// Network is the effective network configuration, set by ConfigureNetwork.
+var Network = NetworkConfig{StrictSSL: true}
+
+// ConfigureNetwork applies cfg to the shared HTTP transport.
+func ConfigureNetwork(cfg NetworkConfig) error {
+	if cfg.Proxy == "" {
+		cfg.Proxy = getenvAny("HTTP_PROXY", "http_proxy")
+	}
+	if cfg.HTTPSProxy == "" {
+		cfg.HTTPSProxy = getenvAny("HTTPS_PROXY", "https_proxy")
+	}
+	if cfg.HTTPSProxy == "" {
+		cfg.HTTPSProxy = cfg.Proxy
+	}
+	if cfg.NoProxy == "" {
+		cfg.NoProxy = getenvAny("NO_PROXY", "no_proxy")
+	}
+
+	cfg.CA = slices.DeleteFunc(slices.Clone(cfg.CA), func(ca string) bool { return strings.TrimSpace(ca) == "" })
+
+	tlsConfig, err := cfg.tlsConfig()
+	if err != nil {
+		return err
+	}
+
+	httpTransport.Proxy = cfg.proxyFunc()
+	httpTransport.TLSClientConfig = tlsConfig
+	httpTransport.CloseIdleConnections()
+	Network = cfg
+	return nil
+}
+
+func (cfg NetworkConfig) proxyFunc() func(*http.Request) (*url.URL, error) {
+	proxy := (&httpproxy.Config{
+		HTTPProxy:  cfg.Proxy,
+		HTTPSProxy: cfg.HTTPSProxy,
+		NoProxy:    cfg.NoProxy,
+	}).ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxy(req.URL)
+	}
+}
+
+func (cfg NetworkConfig) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: !cfg.StrictSSL}
+
+	var pool *x509.CertPool
+	if len(cfg.CA) != 0 || cfg.CAFile != "" {
+		pool = x509.NewCertPool()
+		for _, ca := range cfg.CA {
+			// .npmrc files store certificates on a single line with escaped newlines
+			if !pool.AppendCertsFromPEM([]byte(strings.ReplaceAll(ca, `\n`, "\n"))) {
+				return nil, errors.New("ca: no valid PEM certificate")
+			}
+		}
+		if cfg.CAFile != "" {
+			pem, err := os.ReadFile(cfg.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("read cafile: %w", err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("cafile %s: no valid PEM certificate", cfg.CAFile)
+			}
+		}
+	}
+
+	// NODE_EXTRA_CA_CERTS extends the roots like it does for node itself
+	if extra := os.Getenv("NODE_EXTRA_CA_CERTS"); extra != "" {
+		pem, err := os.ReadFile(extra)
+		if err != nil {
+			return nil, fmt.Errorf("read NODE_EXTRA_CA_CERTS: %w", err)
+		}
+		if pool == nil {
+			if pool, err = x509.SystemCertPool(); err != nil {
+				pool = x509.NewCertPool()
+			}
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("NODE_EXTRA_CA_CERTS %s: no valid PEM certificate", extra)
+		}
+	}
+
+	tlsConfig.RootCAs = pool
+	return tlsConfig, nil
+}
+
+func getenvAny(names ...string) string {
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
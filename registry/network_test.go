@@ -0,0 +1,37 @@
+package registry
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestNetworkConfigProxy(t *testing.T) {
+	proxy := NetworkConfig{
+		Proxy:      "http://proxy.local:8080",
+		HTTPSProxy: "proxy.local:8443",
+		NoProxy:    "internal.example,.corp.example",
+	}.proxyFunc()
+
+	for _, tc := range []struct{ url, want string }{
+		{"http://registry.npmjs.org/react", "http://proxy.local:8080"},
+		{"https://registry.npmjs.org/react", "http://proxy.local:8443"},
+		{"https://internal.example/react", ""},
+		{"https://npm.corp.example/react", ""},
+	} {
+		req, err := http.NewRequest(http.MethodGet, tc.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		u, err := proxy(req)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.url, err)
+		}
+		got := ""
+		if u != nil {
+			got = u.String()
+		}
+		if got != tc.want {
+			t.Errorf("%s: proxy %q, want %q", tc.url, got, tc.want)
+		}
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"slices"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/Masterminds/semver/v3"
 	"github.com/tsukinoko-kun/jmod/utils"
@@ -21,6 +22,18 @@ type npmLatest struct {
 	Version string `json:"version"`
 	Id      string `json:"_id"`
 	Dist    dist   `json:"dist"`
+
+	Os      stringList `json:"os"`
+	Cpu     stringList `json:"cpu"`
+	Libc    stringList `json:"libc"`
+	Engines Engines    `json:"engines"`
+
+	// verified is set if the registry signature was checked
+	verified bool
+}
+
+func (p npmLatest) signatureVerified() bool {
+	return p.verified
 }
 
 type dist struct {
@@ -71,24 +84,16 @@ func (p npmLatest) GetChecksum() []byte {
 	return nil
 }
 
+func (p npmLatest) platformRequirements() PlatformRequirements {
+	return PlatformRequirements{OS: p.Os, CPU: p.Cpu, Libc: p.Libc}
+}
+
 func Npm_GetLatestVersion(pkg string) (string, error) {
 	return Npm_GetVersion(pkg, "latest")
 }
 
 func Npm_GetVersion(pkg string, versionName string) (string, error) {
-	urlPath, err := url.JoinPath("/", pkg, versionName)
-	if err != nil {
-		return "", err
-	}
-	resp, err := http.Get("https://registry.npmjs.org" + urlPath)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	var latest npmLatest
-	jd := json.NewDecoder(resp.Body)
-	err = jd.Decode(&latest)
+	latest, err := npmGetVersion(context.Background(), pkg, versionName)
 	if err != nil {
 		return "", err
 	}
@@ -100,30 +105,62 @@ func Npm_GetVersion(pkg string, versionName string) (string, error) {
 	return latest.Version, nil
 }
 
+// npmGetVersion fetches the manifest of a version or dist-tag of pkg.
+func npmGetVersion(ctx context.Context, pkg string, versionName string) (npmLatest, error) {
+	urlPath, err := url.JoinPath("/", pkg, versionName)
+	if err != nil {
+		return npmLatest{}, err
+	}
+	url := strings.TrimSuffix(NpmRegistry, "/") + urlPath
+	resp, err := doWithRetry(ctx, httpClient, func() (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	})
+	if err != nil {
+		return npmLatest{}, fmt.Errorf("http get %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return npmLatest{}, fmt.Errorf("http get %s: bad status: %s", url, resp.Status)
+	}
+
+	var latest npmLatest
+	if err := json.NewDecoder(resp.Body).Decode(&latest); err != nil {
+		return npmLatest{}, err
+	}
+	return latest, nil
+}
+
 type npmFull struct {
 	Name     string               `json:"name"`
 	Versions map[string]npmLatest `json:"versions"`
+	// Time maps versions to their publish time
+	Time map[string]string `json:"time"`
 }
 
 func Npm_Resolve(ctx context.Context, packageName string, versionConstraint *semver.Constraints) (Resolveable, error) {
+	defer resolveTimer.Start()()
 	urlPath, err := url.JoinPath("/", packageName)
 	if err != nil {
 		return nil, fmt.Errorf("construct URL path: %s", packageName)
 	}
-	url := "https://registry.npmjs.org" + urlPath
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("new request: %w", err)
-	}
-	resp, err := http.DefaultClient.Do(req)
+	url := strings.TrimSuffix(NpmRegistry, "/") + urlPath
+	resp, err := doWithRetry(ctx, httpClient, func() (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("http get %s: %w", url, err)
 	}
-	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("http get %s: bad status: %s", url, resp.Status)
+	}
 
 	var full npmFull
 	jd := json.NewDecoder(resp.Body)
 	err = jd.Decode(&full)
+	// the body holds a request slot of the registry host,
+	// verifying the signatures below requests the same host
+	resp.Body.Close()
 	if err != nil {
 		return nil, fmt.Errorf("decode npm API response %s: %w", url, err)
 	}
@@ -139,11 +176,36 @@ func Npm_Resolve(ctx context.Context, packageName string, versionConstraint *sem
 		}
 	}
 	sort.Sort(semver.Collection(semverVersions))
+	var candidates []npmLatest
 	for _, semverVersion := range slices.Backward(semverVersions) {
 		if npmVersion, ok := full.Versions[semverVersion.String()]; ok {
-			return npmVersion, nil
+			candidates = append(candidates, npmVersion)
 		}
 	}
+	if len(candidates) == 0 {
+		return nil, errors.New("not found")
+	}
 
-	return nil, errors.New("not found")
+	npmVersion := candidates[0]
+	if PreferCompatibleEngines {
+		for _, candidate := range candidates {
+			if len(candidate.Engines.Unsatisfied()) == 0 {
+				npmVersion = candidate
+				break
+			}
+		}
+	}
+	if req := npmVersion.platformRequirements(); !req.Supports(TargetPlatform) {
+		return nil, fmt.Errorf("%w: %s requires %s, target is %s", ErrUnsupportedPlatform, npmVersion.String(), req.String(), TargetPlatform.String())
+	}
+	if err := checkEngines(npmVersion.String(), npmVersion.Engines); err != nil {
+		return nil, err
+	}
+	publishedAt, _ := time.Parse(time.RFC3339, full.Time[npmVersion.Version])
+	verified, err := verifySignatures(ctx, npmVersion, publishedAt)
+	if err != nil {
+		return nil, err
+	}
+	npmVersion.verified = verified
+	return npmVersion, nil
 }
@@ -0,0 +1,161 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+var ErrUnsupportedPlatform = errors.New("unsupported platform")
+
+// Platform describes the environment packages are installed for,
+// using the names npm uses in the os, cpu and libc package.json fields.
+type Platform struct {
+	OS   string
+	CPU  string
+	Libc string
+}
+
+func (p Platform) String() string {
+	if p.Libc != "" {
+		return fmt.Sprintf("%s-%s-%s", p.OS, p.CPU, p.Libc)
+	}
+	return fmt.Sprintf("%s-%s", p.OS, p.CPU)
+}
+
+// TargetPlatform is the platform used to filter packages during resolution.
+// It defaults to the host platform and can be overridden for cross-installs.
+var TargetPlatform = HostPlatform()
+
+// HostPlatform returns the platform jmod is running on.
+func HostPlatform() Platform {
+	return Platform{
+		OS:   NodeOS(runtime.GOOS),
+		CPU:  NodeCPU(runtime.GOARCH),
+		Libc: hostLibc(),
+	}
+}
+
+// NodeOS maps a GOOS value to the equivalent Node.js process.platform value.
+func NodeOS(goos string) string {
+	switch goos {
+	case "windows":
+		return "win32"
+	default:
+		return goos
+	}
+}
+
+// NodeCPU maps a GOARCH value to the equivalent Node.js process.arch value.
+func NodeCPU(goarch string) string {
+	switch goarch {
+	case "amd64":
+		return "x64"
+	case "386":
+		return "ia32"
+	case "ppc64le":
+		return "ppc64"
+	default:
+		return goarch
+	}
+}
+
+func hostLibc() string {
+	if runtime.GOOS != "linux" {
+		return ""
+	}
+	if matches, _ := filepath.Glob("/lib/ld-musl-*.so.1"); len(matches) > 0 {
+		return "musl"
+	}
+	return "glibc"
+}
+
+// stringList decodes a package.json field that may be either a single string or an array of strings.
+// Values of any other type are ignored instead of failing the whole manifest.
+type stringList []string
+
+func (l *stringList) UnmarshalJSON(data []byte) error {
+	*l = nil
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil
+	}
+	switch data[0] {
+	case '"':
+		var s string
+		if err := json.Unmarshal(data, &s); err == nil {
+			*l = stringList{s}
+		}
+	case '[':
+		var list []string
+		if err := json.Unmarshal(data, &list); err == nil {
+			*l = list
+		}
+	}
+	return nil
+}
+
+// PlatformRequirements holds the os, cpu and libc fields of a package manifest.
+type PlatformRequirements struct {
+	OS   stringList `json:"os,omitempty"`
+	CPU  stringList `json:"cpu,omitempty"`
+	Libc stringList `json:"libc,omitempty"`
+}
+
+// Supports reports whether a package with these requirements can be installed on p.
+// The semantics follow npm: entries prefixed with "!" exclude a value,
+// "any" matches everything and libc is only checked on Linux.
+func (r PlatformRequirements) Supports(p Platform) bool {
+	if len(r.OS) > 0 && !checkPlatformList(p.OS, r.OS) {
+		return false
+	}
+	if len(r.CPU) > 0 && !checkPlatformList(p.CPU, r.CPU) {
+		return false
+	}
+	if len(r.Libc) > 0 {
+		if p.OS != "linux" || p.Libc == "" {
+			return false
+		}
+		if !checkPlatformList(p.Libc, r.Libc) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r PlatformRequirements) String() string {
+	var parts []string
+	if len(r.OS) > 0 {
+		parts = append(parts, "os="+strings.Join(r.OS, ","))
+	}
+	if len(r.CPU) > 0 {
+		parts = append(parts, "cpu="+strings.Join(r.CPU, ","))
+	}
+	if len(r.Libc) > 0 {
+		parts = append(parts, "libc="+strings.Join(r.Libc, ","))
+	}
+	return strings.Join(parts, " ")
+}
+
+func checkPlatformList(value string, list []string) bool {
+	if len(list) == 1 && list[0] == "any" {
+		return true
+	}
+	negated := 0
+	match := false
+	for _, entry := range list {
+		if test, ok := strings.CutPrefix(entry, "!"); ok {
+			negated++
+			if value == test {
+				return false
+			}
+		} else if value == entry {
+			match = true
+		}
+	}
+	return match || negated == len(list)
+}
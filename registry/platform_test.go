@@ -0,0 +1,53 @@
+package registry
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPlatformRequirementsSupports(t *testing.T) {
+	linuxGlibc := Platform{OS: "linux", CPU: "x64", Libc: "glibc"}
+	linuxMusl := Platform{OS: "linux", CPU: "arm64", Libc: "musl"}
+	darwin := Platform{OS: "darwin", CPU: "arm64"}
+
+	tests := []struct {
+		name     string
+		manifest string
+		platform Platform
+		want     bool
+	}{
+		{"no requirements", `{}`, darwin, true},
+		{"os match", `{"os":["darwin"]}`, darwin, true},
+		{"os mismatch", `{"os":["linux"]}`, darwin, false},
+		{"os as string", `{"os":"darwin"}`, darwin, true},
+		{"negated os", `{"os":["!win32"]}`, darwin, true},
+		{"negated os excludes", `{"os":["!darwin"]}`, darwin, false},
+		{"any", `{"cpu":["any"]}`, linuxMusl, true},
+		{"cpu mismatch", `{"os":["linux"],"cpu":["x64"]}`, linuxMusl, false},
+		{"libc match", `{"os":["linux"],"cpu":["x64"],"libc":["glibc"]}`, linuxGlibc, true},
+		{"libc mismatch", `{"os":["linux"],"cpu":["arm64"],"libc":["glibc"]}`, linuxMusl, false},
+		{"libc requires linux", `{"libc":["glibc"]}`, darwin, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req PlatformRequirements
+			if err := json.Unmarshal([]byte(tt.manifest), &req); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if got := req.Supports(tt.platform); got != tt.want {
+				t.Errorf("Supports(%s) = %v, want %v", tt.platform, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlatformRequirementsIgnoresInvalidFields(t *testing.T) {
+	var req PlatformRequirements
+	if err := json.Unmarshal([]byte(`{"os":{"linux":true},"cpu":42}`), &req); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(req.OS) != 0 || len(req.CPU) != 0 {
+		t.Errorf("expected invalid fields to be ignored, got %+v", req)
+	}
+}
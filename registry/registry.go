@@ -3,8 +3,30 @@ package registry
 import (
 	"fmt"
 	"strings"
+	"sync"
+
+	"github.com/tsukinoko-kun/jmod/logger"
+)
+
+// NpmRegistry is the base URL of the npm registry.
+var NpmRegistry = "https://registry.npmjs.org"
+
+var (
+	warnedMu sync.Mutex
+	warned   = map[string]struct{}{}
 )
 
+// warnOnce logs a warning the first time it is called with key.
+func warnOnce(key string, format string, args ...any) {
+	warnedMu.Lock()
+	_, ok := warned[key]
+	warned[key] = struct{}{}
+	warnedMu.Unlock()
+	if !ok {
+		logger.Warnf(format, args...)
+	}
+}
+
 type Package struct {
 	PackageName string
 	Version     string
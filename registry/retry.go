@@ -0,0 +1,125 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/tsukinoko-kun/jmod/logger"
+)
+
+var (
+	// FetchRetries is how often a failed registry or tarball request is retried.
+	FetchRetries = 3
+	// FetchRetryMinDelay is the delay before the first retry, it doubles with every further retry.
+	FetchRetryMinDelay = time.Second
+	// FetchRetryMaxDelay caps the delay between retries, including delays requested by Retry-After.
+	FetchRetryMaxDelay = 30 * time.Second
+)
+
+// retryableStatus reports whether a response status is worth retrying.
+func retryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusRequestTimeout || status >= 500
+}
+
+// retryableError reports whether a request error is worth retrying.
+// Only network failures are transient: connection resets, timeouts, DNS hiccups and truncated bodies.
+// Cancellation and local errors like a full disk are not.
+func retryableError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ETIMEDOUT) {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return !dnsErr.IsNotFound
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// retryDelay returns how long to wait before retry number attempt (starting at 0).
+// Retry-After of a 429 or 503 response is honored, otherwise the delay grows exponentially with jitter.
+func retryDelay(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			return min(d, FetchRetryMaxDelay)
+		}
+	}
+	d := FetchRetryMinDelay << attempt
+	if d <= 0 || d > FetchRetryMaxDelay {
+		d = FetchRetryMaxDelay
+	}
+	// equal jitter: half fixed, half random
+	return d/2 + rand.N(d/2+1)
+}
+
+func parseRetryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0), true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
+
+// sleepCtx waits for d or until ctx is done.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-t.C:
+		return nil
+	}
+}
+
+// doWithRetry sends the request created by newReq and retries transient failures up to FetchRetries times.
+// newReq is called for every attempt so request bodies can be recreated.
+// Non-retryable responses are returned as they are for the caller to check.
+func doWithRetry(ctx context.Context, client *http.Client, newReq func() (*http.Request, error)) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := newReq()
+		if err != nil {
+			return nil, fmt.Errorf("new request: %w", err)
+		}
+		resp, err := client.Do(req)
+		if err == nil && !retryableStatus(resp.StatusCode) {
+			return resp, nil
+		}
+		if attempt >= FetchRetries || (err != nil && !retryableError(err)) {
+			return resp, err
+		}
+
+		delay := retryDelay(attempt, resp)
+		if err != nil {
+			logger.Printf("%s %s failed (%s), retrying in %s", req.Method, req.URL, err, delay)
+		} else {
+			logger.Printf("%s %s: %s, retrying in %s", req.Method, req.URL, resp.Status, delay)
+			resp.Body.Close()
+		}
+		if err := sleepCtx(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestDoWithRetry(t *testing.T) {
+	defer func(d time.Duration) { FetchRetryMinDelay = d }(FetchRetryMinDelay)
+	FetchRetryMinDelay = time.Millisecond
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			fmt.Fprint(w, "ok")
+		}
+	}))
+	defer srv.Close()
+
+	resp, err := doWithRetry(context.Background(), srv.Client(), func() (*http.Request, error) {
+		return http.NewRequest(http.MethodGet, srv.URL, nil)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || calls.Load() != 3 {
+		t.Errorf("got status %d after %d calls", resp.StatusCode, calls.Load())
+	}
+}
+
+func TestRetryableError(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"connection reset", &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, true},
+		{"connection refused", &url.Error{Op: "Get", URL: "https://registry.npmjs.org", Err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}}, true},
+		{"dns timeout", &net.DNSError{Err: "timeout", Name: "registry.npmjs.org", IsTimeout: true}, true},
+		{"dns not found", &net.DNSError{Err: "no such host", Name: "registry.invalid", IsNotFound: true}, false},
+		{"truncated body", fmt.Errorf("copy: %w", io.ErrUnexpectedEOF), true},
+		{"canceled", fmt.Errorf("http get: %w", context.Canceled), false},
+		{"disk full", fmt.Errorf("copy: %w", &os.PathError{Op: "write", Path: "pkg.tgz", Err: syscall.ENOSPC}), false},
+		{"bad url", &url.Error{Op: "Get", URL: "ftp://registry", Err: errors.New("unsupported protocol scheme")}, false},
+	} {
+		if got := retryableError(tc.err); got != tc.want {
+			t.Errorf("%s: retryableError(%v) = %v, want %v", tc.name, tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if d, ok := parseRetryAfter("120"); !ok || d != 2*time.Minute {
+		t.Errorf("got %s, %v", d, ok)
+	}
+	if d, ok := parseRetryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)); !ok || d < 59*time.Minute {
+		t.Errorf("got %s, %v", d, ok)
+	}
+	if _, ok := parseRetryAfter("soon"); ok {
+		t.Error("expected invalid Retry-After to be ignored")
+	}
+}
+
+func TestDownloadResume(t *testing.T) {
+	defer func(d time.Duration) { FetchRetryMinDelay = d }(FetchRetryMinDelay)
+	FetchRetryMinDelay = time.Millisecond
+	t.Setenv("JMOD_TARBALL_CACHE", t.TempDir())
+	defer func() { tarballCacheLocation = "" }()
+
+	content := bytes.Repeat([]byte("jmod"), 64*1024)
+	var ranges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		if rng := r.Header.Get("Range"); rng != "" {
+			start, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rng, "bytes="), "-"))
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(content)-1, len(content)))
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)-start))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(content[start:])
+			return
+		}
+		// send half of the body, then drop the connection
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		w.Write(content[:len(content)/2])
+		w.(http.Flusher).Flush()
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	}))
+	defer srv.Close()
+
+	path, sum, err := downloadToTempWithChecksum(context.Background(), srv.URL+"/pkg.tgz", t.TempDir(), ChecksumFormatSha512, "test", "pkg", "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := sha512.Sum512(content)
+	if !bytes.Equal(got, content) || !bytes.Equal(sum, want[:]) {
+		t.Error("resumed download does not match")
+	}
+	if len(ranges) != 2 || ranges[0] != "" || ranges[1] != fmt.Sprintf("bytes=%d-", len(content)/2) {
+		t.Errorf("unexpected requests %q", ranges)
+	}
+}
+
+func TestDownloadResumeRangeMismatch(t *testing.T) {
+	defer func(d time.Duration) { FetchRetryMinDelay = d }(FetchRetryMinDelay)
+	FetchRetryMinDelay = time.Millisecond
+	t.Setenv("JMOD_TARBALL_CACHE", t.TempDir())
+	defer func() { tarballCacheLocation = "" }()
+
+	content := bytes.Repeat([]byte("jmod"), 1024)
+	var ranges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		if r.Header.Get("Range") != "" {
+			// answer every range request from the start
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-%d/%d", len(content)-1, len(content)))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(content)
+			return
+		}
+		w.Write(content)
+	}))
+	defer srv.Close()
+
+	url := srv.URL + "/pkg.tgz"
+	destDir := t.TempDir()
+	partial := filepath.Join(destDir, fmt.Sprintf(".download-%x.partial", sha256.Sum256([]byte(url))))
+	if err := os.WriteFile(partial, content[:100], 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	path, _, err := downloadToTempWithChecksum(context.Background(), url, destDir, ChecksumFormatSha512, "test", "pkg", "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("expected the download to restart, got %d bytes", len(got))
+	}
+	if len(ranges) != 2 || ranges[0] != "bytes=100-" || ranges[1] != "" {
+		t.Errorf("unexpected requests %q", ranges)
+	}
+}
+
+func TestDownloadNotFoundRemovesPartial(t *testing.T) {
+	t.Setenv("JMOD_TARBALL_CACHE", t.TempDir())
+	defer func() { tarballCacheLocation = "" }()
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	pkgURL := srv.URL + "/pkg.tgz"
+	destDir := t.TempDir()
+	partial := filepath.Join(destDir, fmt.Sprintf(".download-%x.partial", sha256.Sum256([]byte(pkgURL))))
+	if err := os.WriteFile(partial, []byte("jmod"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := downloadToTempWithChecksum(context.Background(), pkgURL, destDir, ChecksumFormatSha512, "test", "pkg", "1.0.0"); err == nil {
+		t.Fatal("expected the download to fail")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("expected a 404 not to be retried, got %d requests", calls.Load())
+	}
+	if _, err := os.Stat(partial); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the partial download to be removed, got %v", err)
+	}
+}
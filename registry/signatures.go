@@ -0,0 +1,244 @@
+package registry
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tsukinoko-kun/jmod/logger"
+)
+
+var ErrInvalidSignature = errors.New("invalid registry signature")
+
+type SignatureMode uint8
+
+const (
+	// SignatureModeWarn logs invalid or missing signatures
+	SignatureModeWarn SignatureMode = iota
+	// SignatureModeStrict fails the resolution of packages with invalid or missing signatures
+	SignatureModeStrict
+	// SignatureModeOff skips the verification
+	SignatureModeOff
+)
+
+func ParseSignatureMode(s string) (SignatureMode, error) {
+	switch s {
+	case "", "warn":
+		return SignatureModeWarn, nil
+	case "strict":
+		return SignatureModeStrict, nil
+	case "off":
+		return SignatureModeOff, nil
+	default:
+		return SignatureModeWarn, fmt.Errorf("invalid signature mode %q (expected warn, strict or off)", s)
+	}
+}
+
+var (
+	// Signatures configures how registry signatures of resolved packages are verified.
+	Signatures SignatureMode
+	// NpmKeysURL overrides the URL the registry signing keys are fetched from.
+	// Defaults to <NpmRegistry>/-/npm/v1/keys.
+	NpmKeysURL string
+)
+
+type registryKey struct {
+	Expires *time.Time `json:"expires"`
+	Keyid   string     `json:"keyid"`
+	Keytype string     `json:"keytype"`
+	Scheme  string     `json:"scheme"`
+	Key     string     `json:"key"`
+}
+
+var (
+	registryKeysMu sync.Mutex
+	// registryKeys is nil until the keys were fetched successfully
+	registryKeys map[string]registryKey
+)
+
+func npmKeysURL() string {
+	if NpmKeysURL != "" {
+		return NpmKeysURL
+	}
+	return strings.TrimSuffix(NpmRegistry, "/") + "/-/npm/v1/keys"
+}
+
+// getRegistryKeys fetches the registry signing keys once per process.
+// Failed fetches are not cached, the next verification tries again.
+// A registry without a keys endpoint results in no keys and no error.
+func getRegistryKeys(ctx context.Context) (map[string]registryKey, error) {
+	registryKeysMu.Lock()
+	defer registryKeysMu.Unlock()
+	if registryKeys != nil {
+		return registryKeys, nil
+	}
+	keys, err := fetchRegistryKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	registryKeys = keys
+	return keys, nil
+}
+
+func fetchRegistryKeys(ctx context.Context) (map[string]registryKey, error) {
+	url := npmKeysURL()
+	resp, err := doWithRetry(ctx, httpClient, func() (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("http get %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return map[string]registryKey{}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http get %s: bad status: %s", url, resp.Status)
+	}
+	var body struct {
+		Keys []registryKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", url, err)
+	}
+	keys := make(map[string]registryKey, len(body.Keys))
+	for _, key := range body.Keys {
+		keys[key.Keyid] = key
+	}
+	return keys, nil
+}
+
+// verifySignatures checks the registry signature over name@version:integrity.
+// publishedAt is used to reject signatures made with a key after it expired and may be zero if unknown.
+// verified is only set if the signature was checked successfully.
+func verifySignatures(ctx context.Context, p npmLatest, publishedAt time.Time) (verified bool, err error) {
+	if Signatures == SignatureModeOff {
+		return false, nil
+	}
+	err = checkSignatures(ctx, p, publishedAt)
+	if err == nil {
+		return true, nil
+	}
+	if Signatures == SignatureModeStrict {
+		return false, err
+	}
+	warnOnce(err.Error(), "%s", err)
+	return false, nil
+}
+
+// signatureMarker is created next to the package directory of cache entries whose signature was verified.
+const signatureMarker = "signature-verified"
+
+// signatureFailedMarker is created next to the package directory of cache entries that failed verification,
+// its modification time is the time of the last check.
+const signatureFailedMarker = "signature-failed"
+
+// signatureRecheckInterval is how long warn mode uses a cache entry that failed verification without checking it again.
+const signatureRecheckInterval = 24 * time.Hour
+
+// verifyCached verifies the signature of a cached npm package that was not verified when it was downloaded,
+// for example because signatures were off back then. It reports whether the entry may be used,
+// in strict mode unverifiable entries are skipped so the resolution reports the failure.
+func verifyCached(ctx context.Context, registry string, name string, version string, versionDir string) bool {
+	if registry != "npm" || Signatures == SignatureModeOff {
+		return true
+	}
+	if _, err := os.Stat(filepath.Join(versionDir, signatureMarker)); err == nil {
+		return true
+	}
+	failedMarker := filepath.Join(versionDir, signatureFailedMarker)
+	if Signatures == SignatureModeWarn {
+		if info, err := os.Stat(failedMarker); err == nil && time.Since(info.ModTime()) < signatureRecheckInterval {
+			return true
+		}
+	}
+	p, err := npmGetVersion(ctx, name, version)
+	if err == nil {
+		err = checkSignatures(ctx, p, time.Time{})
+	}
+	if err == nil {
+		_ = os.Remove(failedMarker)
+		if err := os.WriteFile(filepath.Join(versionDir, signatureMarker), nil, 0o644); err != nil {
+			logger.Printf("failed to mark %s@%s as verified: %s", name, version, err)
+		}
+		return true
+	}
+	if ctx.Err() != nil {
+		return Signatures != SignatureModeStrict
+	}
+	if err := os.WriteFile(failedMarker, []byte(time.Now().Format(time.RFC3339)), 0o644); err != nil {
+		logger.Printf("failed to mark %s@%s as unverified: %s", name, version, err)
+	}
+	if Signatures == SignatureModeStrict {
+		logger.Printf("cached %s@%s failed verification: %s", name, version, err)
+		return false
+	}
+	warnOnce(err.Error(), "%s", err)
+	return true
+}
+
+func checkSignatures(ctx context.Context, p npmLatest, publishedAt time.Time) error {
+	keys, err := getRegistryKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: failed to fetch registry keys: %w", ErrInvalidSignature, err)
+	}
+	if len(keys) == 0 {
+		// registry does not sign packages
+		return nil
+	}
+	if len(p.Dist.Signatures) == 0 {
+		return fmt.Errorf("%w: %s has no signature", ErrInvalidSignature, p.String())
+	}
+
+	message := []byte(fmt.Sprintf("%s@%s:%s", p.Name, p.Version, p.Dist.Integrity))
+	digest := sha256.Sum256(message)
+	for _, sig := range p.Dist.Signatures {
+		key, ok := keys[sig.Keyid]
+		if !ok {
+			continue
+		}
+		if key.Expires != nil && !publishedAt.IsZero() && key.Expires.Before(publishedAt) {
+			return fmt.Errorf("%w: %s was signed with key %s which expired before the package was published", ErrInvalidSignature, p.String(), sig.Keyid)
+		}
+		pub, err := parseRegistryKey(key)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrInvalidSignature, p.String(), err)
+		}
+		rawSig, err := base64.StdEncoding.DecodeString(sig.Sig)
+		if err != nil {
+			return fmt.Errorf("%w: %s: decode signature: %w", ErrInvalidSignature, p.String(), err)
+		}
+		if !ecdsa.VerifyASN1(pub, digest[:], rawSig) {
+			return fmt.Errorf("%w: %s signature does not match key %s", ErrInvalidSignature, p.String(), sig.Keyid)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: %s is not signed with a known registry key", ErrInvalidSignature, p.String())
+}
+
+func parseRegistryKey(key registryKey) (*ecdsa.PublicKey, error) {
+	der, err := base64.StdEncoding.DecodeString(key.Key)
+	if err != nil {
+		return nil, fmt.Errorf("decode key %s: %w", key.Keyid, err)
+	}
+	pub, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("parse key %s: %w", key.Keyid, err)
+	}
+	ecdsaPub, ok := pub.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("key %s is not an ECDSA key", key.Keyid)
+	}
+	return ecdsaPub, nil
+}
@@ -0,0 +1,216 @@
+package registry
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+func TestVerifySignatures(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expires := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	keysServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]any{{
+				"expires": expires,
+				"keyid":   "SHA256:test",
+				"keytype": "ecdsa-sha2-nistp256",
+				"scheme":  "ecdsa-sha2-nistp256",
+				"key":     base64.StdEncoding.EncodeToString(der),
+			}},
+		})
+	}))
+	defer keysServer.Close()
+
+	NpmKeysURL = keysServer.URL
+	Signatures = SignatureModeStrict
+	registryKeys = nil
+	defer func() {
+		NpmKeysURL = ""
+		Signatures = SignatureModeWarn
+		registryKeys = nil
+	}()
+
+	sign := func(message string) string {
+		digest := sha256.Sum256([]byte(message))
+		sig, err := ecdsa.SignASN1(rand.Reader, priv, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.StdEncoding.EncodeToString(sig)
+	}
+	pkg := func(sig string) npmLatest {
+		p := npmLatest{Name: "example", Version: "1.0.0", Dist: dist{Integrity: "sha512-abc"}}
+		if sig != "" {
+			p.Dist.Signatures = []signature{{Sig: sig, Keyid: "SHA256:test"}}
+		}
+		return p
+	}
+	published := expires.Add(-time.Hour)
+
+	if _, err := verifySignatures(context.Background(), pkg(sign("example@1.0.0:sha512-abc")), published); err != nil {
+		t.Errorf("valid signature: %v", err)
+	}
+	if _, err := verifySignatures(context.Background(), pkg(sign("example@1.0.1:sha512-abc")), published); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("signature over other version: expected ErrInvalidSignature, got %v", err)
+	}
+	if _, err := verifySignatures(context.Background(), pkg(""), published); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("missing signature: expected ErrInvalidSignature, got %v", err)
+	}
+	if _, err := verifySignatures(context.Background(), pkg(sign("example@1.0.0:sha512-abc")), expires.Add(time.Hour)); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expired key: expected ErrInvalidSignature, got %v", err)
+	}
+
+	Signatures = SignatureModeWarn
+	if _, err := verifySignatures(context.Background(), pkg(""), published); err != nil {
+		t.Errorf("warn mode should not fail: %v", err)
+	}
+}
+
+func TestRegistryKeysRetryAfterFailure(t *testing.T) {
+	keysServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"keys":[{"keyid":"SHA256:test","key":"a2V5"}]}`))
+	}))
+	defer keysServer.Close()
+
+	NpmKeysURL = keysServer.URL
+	registryKeys = nil
+	defer func() {
+		NpmKeysURL = ""
+		registryKeys = nil
+	}()
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := getRegistryKeys(cancelled); err == nil {
+		t.Fatal("expected the fetch with a cancelled context to fail")
+	}
+	keys, err := getRegistryKeys(context.Background())
+	if err != nil {
+		t.Fatalf("a failed fetch must not be cached: %v", err)
+	}
+	if _, ok := keys["SHA256:test"]; !ok {
+		t.Errorf("expected the key to be fetched, got %v", keys)
+	}
+}
+
+func TestVerifyCached(t *testing.T) {
+	var versionRequests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/-/npm/v1/keys":
+			_, _ = w.Write([]byte(`{"keys":[{"keyid":"SHA256:test","key":"a2V5"}]}`))
+		case "/example/1.0.0":
+			versionRequests.Add(1)
+			_, _ = w.Write([]byte(`{"name":"example","version":"1.0.0","dist":{"integrity":"sha512-abc"}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	defer func(registry string) { NpmRegistry = registry }(NpmRegistry)
+	NpmRegistry = srv.URL
+	Signatures = SignatureModeStrict
+	registryKeys = nil
+	defer func() {
+		Signatures = SignatureModeWarn
+		registryKeys = nil
+	}()
+
+	dir := t.TempDir()
+	if verifyCached(context.Background(), "npm", "example", "1.0.0", dir) {
+		t.Error("an unsigned cache entry must not be used in strict mode")
+	}
+	if _, err := os.Stat(filepath.Join(dir, signatureFailedMarker)); err != nil {
+		t.Errorf("expected the failed verification to be recorded: %v", err)
+	}
+
+	Signatures = SignatureModeWarn
+	before := versionRequests.Load()
+	if !verifyCached(context.Background(), "npm", "example", "1.0.0", dir) {
+		t.Error("warn mode must use an unverified cache entry")
+	}
+	if versionRequests.Load() != before {
+		t.Error("warn mode must not check a recently failed cache entry again")
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := os.Remove(filepath.Join(dir, signatureFailedMarker)); err != nil {
+		t.Fatal(err)
+	}
+	Signatures = SignatureModeStrict
+	if verifyCached(cancelled, "npm", "example", "1.0.0", dir) {
+		t.Error("a cancelled verification must not pass in strict mode")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, signatureMarker), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if !verifyCached(context.Background(), "npm", "example", "1.0.0", dir) {
+		t.Error("a verified cache entry must be used without verifying it again")
+	}
+}
+
+func TestResolveWithOneRequestPerHost(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/-/npm/v1/keys":
+			_, _ = w.Write([]byte(`{"keys":[]}`))
+		default:
+			_, _ = fmt.Fprintf(w, `{"name":%q,"versions":{"1.0.0":{"name":%[1]q,"version":"1.0.0","dist":{"integrity":"sha512-abc"}}}}`, strings.TrimPrefix(r.URL.Path, "/"))
+		}
+	}))
+	defer srv.Close()
+
+	defer func(registry string, n int) {
+		NpmRegistry = registry
+		MaxRequestsPerHost = n
+	}(NpmRegistry, MaxRequestsPerHost)
+	NpmRegistry = srv.URL
+	MaxRequestsPerHost = 1
+	registryKeys = nil
+	defer func() { registryKeys = nil }()
+
+	constraint, err := semver.NewConstraint("^1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Go(func() {
+			if _, err := Npm_Resolve(ctx, fmt.Sprintf("pkg%d", i), constraint); err != nil {
+				t.Errorf("resolve pkg%d: %v", i, err)
+			}
+		})
+	}
+	wg.Wait()
+}
@@ -0,0 +1,99 @@
+package registry
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// FetchStats counts the packages of the installs since the program started.
+type FetchStats struct {
+	// Resolved is the number of distinct registry packages
+	Resolved int
+	// Downloaded is the number of packages fetched from the registry
+	Downloaded int
+	// Cached is the number of packages served from the package or tarball cache
+	Cached int
+	// Bytes is the size of all tarball downloads
+	Bytes int64
+	// ResolveTime is the wall time spent resolving versions from the registry
+	ResolveTime time.Duration
+	// FetchTime is the wall time spent downloading and extracting packages
+	FetchTime time.Duration
+}
+
+var (
+	statsMu         sync.Mutex
+	statsResolved   = make(map[string]struct{})
+	statsDownloaded = make(map[string]struct{})
+	statsBytes      atomic.Int64
+	resolveTimer    PhaseTimer
+	fetchTimer      PhaseTimer
+)
+
+// PhaseTimer measures the wall time during which at least one operation of a phase runs.
+// Packages are resolved, fetched and linked concurrently, so the phases of an install overlap.
+type PhaseTimer struct {
+	mu     sync.Mutex
+	active int
+	since  time.Time
+	total  time.Duration
+}
+
+// Start marks the beginning of an operation, the returned function its end.
+func (t *PhaseTimer) Start() (stop func()) {
+	t.mu.Lock()
+	if t.active == 0 {
+		t.since = time.Now()
+	}
+	t.active++
+	t.mu.Unlock()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.mu.Lock()
+			t.active--
+			if t.active == 0 {
+				t.total += time.Since(t.since)
+			}
+			t.mu.Unlock()
+		})
+	}
+}
+
+// Elapsed returns the time during which operations ran, including the ones still running.
+func (t *PhaseTimer) Elapsed() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.active > 0 {
+		return t.total + time.Since(t.since)
+	}
+	return t.total
+}
+
+// RecordResolved counts a resolved registry package, every version is counted once.
+func RecordResolved(name, version string) {
+	statsMu.Lock()
+	statsResolved[name+"@"+version] = struct{}{}
+	statsMu.Unlock()
+}
+
+func recordDownloaded(name, version string) {
+	statsMu.Lock()
+	statsDownloaded[name+"@"+version] = struct{}{}
+	statsMu.Unlock()
+}
+
+// Stats returns the counts of all installs so far.
+func Stats() FetchStats {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	return FetchStats{
+		Resolved:    len(statsResolved),
+		Downloaded:  len(statsDownloaded),
+		Cached:      len(statsResolved) - len(statsDownloaded),
+		Bytes:       statsBytes.Load(),
+		ResolveTime: resolveTimer.Elapsed(),
+		FetchTime:   fetchTimer.Elapsed(),
+	}
+}
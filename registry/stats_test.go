@@ -0,0 +1,62 @@
+package registry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	statsMu.Lock()
+	statsResolved = make(map[string]struct{})
+	statsDownloaded = make(map[string]struct{})
+	statsMu.Unlock()
+	statsBytes.Store(0)
+
+	RecordResolved("a", "1.0.0")
+	RecordResolved("a", "1.0.0")
+	RecordResolved("a", "2.0.0")
+	RecordResolved("b", "1.0.0")
+	recordDownloaded("a", "2.0.0")
+	recordDownloaded("a", "2.0.0")
+	statsBytes.Add(1024)
+
+	got := Stats()
+	if got.Resolved != 3 || got.Downloaded != 1 || got.Cached != 2 || got.Bytes != 1024 {
+		t.Errorf("unexpected stats %+v", got)
+	}
+}
+
+func TestPhaseTimer(t *testing.T) {
+	var timer PhaseTimer
+	if timer.Elapsed() != 0 {
+		t.Fatalf("expected a new timer to be zero, got %s", timer.Elapsed())
+	}
+
+	// overlapping operations count once
+	stopA := timer.Start()
+	stopB := timer.Start()
+	time.Sleep(20 * time.Millisecond)
+	stopA()
+	stopA()
+	time.Sleep(20 * time.Millisecond)
+	stopB()
+	first := timer.Elapsed()
+	if first < 40*time.Millisecond || first > 200*time.Millisecond {
+		t.Errorf("expected about 40ms, got %s", first)
+	}
+
+	// idle time between operations is not counted
+	time.Sleep(50 * time.Millisecond)
+	if timer.Elapsed() != first {
+		t.Errorf("expected the idle timer to stay at %s, got %s", first, timer.Elapsed())
+	}
+	stop := timer.Start()
+	time.Sleep(10 * time.Millisecond)
+	if running := timer.Elapsed(); running <= first {
+		t.Errorf("expected a running operation to count, got %s", running)
+	}
+	stop()
+	if total := timer.Elapsed(); total < first+10*time.Millisecond || total > first+150*time.Millisecond {
+		t.Errorf("expected about %s, got %s", first+10*time.Millisecond, total)
+	}
+}
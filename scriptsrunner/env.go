@@ -6,7 +6,11 @@ import (
 	"os/exec"
 	"runtime"
 	"slices"
+	"strconv"
 	"strings"
+	"sync"
+
+	"github.com/tsukinoko-kun/jmod/registry"
 )
 
 func npmConfigProduction() string {
@@ -43,32 +47,95 @@ func getDefaultEnv() []string {
 		"npm_config_global=false",
 		"npm_config_production="+npmConfigProduction(),
 		"npm_config_save="+npmConfigSafe(),
-		"npm_config_registry=https://registry.npmjs.org/",
+		"npm_config_registry="+strings.TrimSuffix(registry.NpmRegistry, "/")+"/",
 		"npm_config__jsr_registry=https://npm.jsr.io/",
 		"NODE_ENV=production",
-		"npm_config_arch="+arch,
-		"npm_config_platform="+runtime.GOOS,
+		"npm_config_arch="+registry.TargetPlatform.CPU,
+		"npm_config_platform="+registry.TargetPlatform.OS,
 		"npm_config_tmp="+os.TempDir(),
 		"npm_node_execpath="+getDefaultJsRunner(),
 	)
 
-	nodeGyp, err := exec.LookPath("node-gyp")
-	if err == nil {
+	defaultEnv = append(defaultEnv, networkEnv(registry.Network)...)
+
+	if registry.TargetPlatform.Libc != "" {
+		defaultEnv = append(defaultEnv, "npm_config_libc="+registry.TargetPlatform.Libc)
+	}
+
+	if nodeGyp, ok := nodeGypPath(); ok {
 		defaultEnv = append(defaultEnv, "npm_config_node_gyp="+nodeGyp)
 	}
 
 	nodeVersion := "?"
+	if v, ok := NodeVersion(); ok {
+		nodeVersion = v
+		defaultEnv = append(defaultEnv, "NODE_VERSION="+nodeVersion)
+		// node-gyp and prebuild tools expect the node version to build against in npm_config_target
+		defaultEnv = append(defaultEnv,
+			"npm_config_target="+strings.TrimPrefix(nodeVersion, "v"),
+			"npm_config_runtime=node",
+			"npm_config_target_arch="+registry.TargetPlatform.CPU,
+		)
+	}
 	if node, err := exec.LookPath("node"); err == nil {
-		if out, err := exec.Command(node, "--version").Output(); err == nil {
-			nodeVersion = strings.TrimSpace(string(out))
-			defaultEnv = append(defaultEnv, "NODE_VERSION="+nodeVersion)
-		}
 		if out, err := exec.Command(node, "-p", "process.versions.napi").Output(); err == nil {
 			nApiVersion := strings.TrimSpace(string(out))
-			defaultEnv = append(defaultEnv, "npm_config_target="+nApiVersion)
+			defaultEnv = append(defaultEnv, "npm_config_napi_build_version="+nApiVersion)
+		}
+		if nodeDir, ok := nodeHeadersDir(node); ok {
+			defaultEnv = append(defaultEnv, "npm_config_nodedir="+nodeDir)
 		}
 	}
 	defaultEnv = append(defaultEnv, fmt.Sprintf("npm_config_user_agent=npm/? node/%s %s %s", nodeVersion, runtime.GOOS, arch))
 
 	return defaultEnv
 }
+
+// networkEnv exposes the proxy and TLS options to scripts the way npm does.
+func networkEnv(network registry.NetworkConfig) []string {
+	env := []string{
+		"npm_config_strict_ssl=" + strconv.FormatBool(network.StrictSSL),
+	}
+	if network.Proxy != "" {
+		env = append(env, "npm_config_proxy="+network.Proxy)
+	}
+	if network.HTTPSProxy != "" {
+		env = append(env, "npm_config_https_proxy="+network.HTTPSProxy)
+	}
+	if network.NoProxy != "" {
+		env = append(env, "npm_config_noproxy="+network.NoProxy)
+	}
+	if network.CAFile != "" {
+		env = append(env, "npm_config_cafile="+network.CAFile)
+	}
+	if len(network.CA) != 0 {
+		env = append(env, "npm_config_ca="+strings.Join(network.CA, "\n"))
+	}
+	return env
+}
+
+// NodeVersion returns the output of `node --version` (e.g. v20.11.0).
+// ok is false if node is not installed.
+var NodeVersion = sync.OnceValues(func() (version string, ok bool) {
+	return commandVersion("node", "--version")
+})
+
+// NpmVersion returns the output of `npm --version`.
+// ok is false if npm is not installed.
+var NpmVersion = sync.OnceValues(func() (version string, ok bool) {
+	return commandVersion("npm", "--version")
+})
+
+// commandVersion runs name with args and returns its trimmed output.
+func commandVersion(name string, args ...string) (string, bool) {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return "", false
+	}
+	out, err := exec.Command(path, args...).Output()
+	if err != nil {
+		return "", false
+	}
+	version := strings.TrimSpace(string(out))
+	return version, version != ""
+}
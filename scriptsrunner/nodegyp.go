@@ -0,0 +1,91 @@
+package scriptsrunner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/tsukinoko-kun/jmod/logger"
+)
+
+var ErrNodeGypNotFound = errors.New("node-gyp not found")
+
+// nodeGypPath returns the node-gyp executable from PATH or the node-gyp.js bundled with npm.
+var nodeGypPath = sync.OnceValues(func() (string, bool) {
+	if nodeGyp, err := exec.LookPath("node-gyp"); err == nil {
+		return nodeGyp, true
+	}
+	// <prefix>/lib/node_modules/npm/bin/npm-cli.js
+	if npm, err := exec.LookPath("npm"); err == nil {
+		if npmCli, err := filepath.EvalSymlinks(npm); err == nil {
+			bundled := filepath.Join(filepath.Dir(filepath.Dir(npmCli)), "node_modules", "node-gyp", "bin", "node-gyp.js")
+			if _, err := os.Stat(bundled); err == nil {
+				return bundled, true
+			}
+		}
+	}
+	return "", false
+})
+
+// nodeHeadersDir returns the installation prefix of node if it ships its headers,
+// so node-gyp does not need to download them.
+func nodeHeadersDir(node string) (string, bool) {
+	resolved, err := filepath.EvalSymlinks(node)
+	if err != nil {
+		return "", false
+	}
+	prefix := filepath.Dir(filepath.Dir(resolved))
+	if _, err := os.Stat(filepath.Join(prefix, "include", "node", "node.h")); err != nil {
+		return "", false
+	}
+	return prefix, true
+}
+
+// NodeABI returns the module ABI version of the installed node (process.versions.modules).
+// ok is false if node is not installed.
+var NodeABI = sync.OnceValues(func() (abi string, ok bool) {
+	return commandVersion("node", "-p", "process.versions.modules")
+})
+
+// RunNodeGyp runs `node-gyp rebuild` for the package,
+// which npm does implicitly for packages with a binding.gyp and no install or preinstall script.
+func RunNodeGyp(packageJsonPath string, sandbox *Sandbox) error {
+	root := filepath.Dir(packageJsonPath)
+	nodeGyp, ok := nodeGypPath()
+	if !ok {
+		return fmt.Errorf("%w: install node-gyp to build the native addon in %s", ErrNodeGypNotFound, root)
+	}
+
+	env := scriptEnv(packageJsonPath, "install", "install")
+	env = append(env, "npm_lifecycle_script=node-gyp rebuild")
+
+	var cmd *exec.Cmd
+	if filepath.Ext(nodeGyp) == ".js" {
+		node, err := exec.LookPath("node")
+		if err != nil {
+			return fmt.Errorf("%w: node is required to run %s", ErrNodeGypNotFound, nodeGyp)
+		}
+		cmd = exec.Command(node, nodeGyp, "rebuild")
+	} else {
+		cmd = exec.Command(nodeGyp, "rebuild")
+	}
+	cmd.Dir = root
+	cmd.Env = env
+	if err := sandbox.apply(cmd); err != nil {
+		return err
+	}
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if len(out) > 0 {
+			return fmt.Errorf("%s: %w", string(out), err)
+		}
+		return err
+	}
+	logger.Printf("%s $ node-gyp rebuild\n%s", root, strings.TrimSpace(string(out)))
+	return nil
+}
@@ -0,0 +1,70 @@
+package scriptsrunner
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/tsukinoko-kun/jmod/logger"
+)
+
+var ErrSandboxUnavailable = errors.New("script sandbox unavailable")
+
+// SandboxCommand is the hidden jmod subcommand the sandboxed script is started with.
+// It restricts its own filesystem access before executing the script.
+const SandboxCommand = "__sandbox-exec"
+
+// Sandbox restricts a script to write access in its package directory and the temp dir,
+// without network access and without secrets in its environment.
+// Filesystem, network and syscall restrictions are only supported on Linux, other systems only scrub the environment.
+type Sandbox struct {
+	// Network allows network access
+	Network bool
+	// Env lists sensitive environment variables that are passed to the script anyway
+	Env []string
+	// Write lists additional writable paths
+	Write []string
+}
+
+// sensitiveEnvSuffixes mark environment variables that are removed in the sandbox
+var sensitiveEnvSuffixes = []string{"_TOKEN", "_SECRET", "_PASSWORD", "_PASSPHRASE", "_API_KEY", "_ACCESS_KEY", "_PRIVATE_KEY", "_CREDENTIALS"}
+
+func isSensitiveEnv(name string) bool {
+	upper := strings.ToUpper(name)
+	switch upper {
+	case "SSH_AUTH_SOCK", "GPG_AGENT_INFO", "NPM_CONFIG__AUTH", "NPM_CONFIG__AUTHTOKEN", "NPM_CONFIG__PASSWORD":
+		return true
+	}
+	// registry scoped auth like npm_config_//registry.npmjs.org/:_authToken
+	if strings.HasPrefix(upper, "NPM_CONFIG_//") {
+		return true
+	}
+	for _, suffix := range sensitiveEnvSuffixes {
+		if strings.HasSuffix(upper, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// scrubEnv removes sensitive variables not explicitly allowed by s.Env.
+func (s *Sandbox) scrubEnv(env []string) []string {
+	scrubbed := make([]string, 0, len(env))
+	for _, e := range env {
+		name, _, _ := strings.Cut(e, "=")
+		if isSensitiveEnv(name) && !slices.Contains(s.Env, name) {
+			continue
+		}
+		scrubbed = append(scrubbed, e)
+	}
+	return scrubbed
+}
+
+var sandboxWarnOnce sync.Once
+
+func warnSandboxUnsupported() {
+	sandboxWarnOnce.Do(func() {
+		logger.Warnf("script sandbox is only supported on Linux, dependency scripts run with full filesystem and network access")
+	})
+}
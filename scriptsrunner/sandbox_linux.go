@@ -0,0 +1,131 @@
+package scriptsrunner
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// sandboxDevices are writable in the sandbox regardless of the configuration
+var sandboxDevices = []string{"/dev/null"}
+
+// apply makes cmd run through SandboxCommand in a new user and network namespace.
+// A nil sandbox leaves cmd unchanged.
+func (s *Sandbox) apply(cmd *exec.Cmd) error {
+	if s == nil {
+		return nil
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrSandboxUnavailable, err)
+	}
+	cmd.Env = s.scrubEnv(cmd.Env)
+
+	args := []string{self, SandboxCommand}
+	writable := append([]string{cmd.Dir, os.TempDir()}, s.Write...)
+	for _, w := range append(writable, sandboxDevices...) {
+		args = append(args, "--write", w)
+	}
+	args = append(args, "--", cmd.Path)
+	args = append(args, cmd.Args[1:]...)
+	cmd.Path = self
+	cmd.Args = args
+
+	if !s.Network {
+		cmd.SysProcAttr = &syscall.SysProcAttr{
+			Cloneflags:                 syscall.CLONE_NEWUSER | syscall.CLONE_NEWNET,
+			UidMappings:                []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}},
+			GidMappings:                []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}},
+			GidMappingsEnableSetgroups: false,
+		}
+	}
+	return nil
+}
+
+// SandboxExec restricts write access of the current process to the writable paths using landlock,
+// denies privileged syscalls using seccomp and replaces it with argv. It is the implementation of SandboxCommand.
+func SandboxExec(writable []string, argv []string) error {
+	if len(argv) == 0 {
+		return fmt.Errorf("%w: no command", ErrSandboxUnavailable)
+	}
+	// landlock and no_new_privs apply to the calling thread, which is the one that executes argv
+	runtime.LockOSThread()
+
+	abi, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, unix.LANDLOCK_CREATE_RULESET_VERSION)
+	if errno != 0 {
+		return fmt.Errorf("%w: landlock: %w", ErrSandboxUnavailable, errno)
+	}
+
+	// reading and executing stays allowed everywhere, only modifications are restricted
+	var handled uint64 = unix.LANDLOCK_ACCESS_FS_WRITE_FILE |
+		unix.LANDLOCK_ACCESS_FS_REMOVE_DIR |
+		unix.LANDLOCK_ACCESS_FS_REMOVE_FILE |
+		unix.LANDLOCK_ACCESS_FS_MAKE_CHAR |
+		unix.LANDLOCK_ACCESS_FS_MAKE_DIR |
+		unix.LANDLOCK_ACCESS_FS_MAKE_REG |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SOCK |
+		unix.LANDLOCK_ACCESS_FS_MAKE_FIFO |
+		unix.LANDLOCK_ACCESS_FS_MAKE_BLOCK |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SYM
+	if abi >= 2 {
+		handled |= unix.LANDLOCK_ACCESS_FS_REFER
+	}
+	if abi >= 3 {
+		handled |= unix.LANDLOCK_ACCESS_FS_TRUNCATE
+	}
+
+	attr := unix.LandlockRulesetAttr{Access_fs: handled}
+	rulesetFd, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if errno != 0 {
+		return fmt.Errorf("%w: create landlock ruleset: %w", ErrSandboxUnavailable, errno)
+	}
+	defer unix.Close(int(rulesetFd))
+
+	for _, path := range writable {
+		if err := addLandlockRule(int(rulesetFd), path, handled); err != nil {
+			return err
+		}
+	}
+
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("%w: set no_new_privs: %w", ErrSandboxUnavailable, err)
+	}
+	if _, _, errno := unix.Syscall(unix.SYS_LANDLOCK_RESTRICT_SELF, rulesetFd, 0, 0); errno != 0 {
+		return fmt.Errorf("%w: landlock restrict self: %w", ErrSandboxUnavailable, errno)
+	}
+	if err := applySeccomp(); err != nil {
+		return err
+	}
+
+	return syscall.Exec(argv[0], argv, os.Environ())
+}
+
+func addLandlockRule(rulesetFd int, path string, handled uint64) error {
+	fd, err := unix.Open(path, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if err != nil {
+		// paths that do not exist can not be written to anyway
+		return nil
+	}
+	defer unix.Close(fd)
+
+	var stat unix.Stat_t
+	if err := unix.Fstat(fd, &stat); err != nil {
+		return fmt.Errorf("%w: stat %s: %w", ErrSandboxUnavailable, path, err)
+	}
+	allowed := handled
+	if stat.Mode&unix.S_IFMT != unix.S_IFDIR {
+		// rules on files may only contain file access rights
+		allowed &= unix.LANDLOCK_ACCESS_FS_WRITE_FILE | unix.LANDLOCK_ACCESS_FS_TRUNCATE
+	}
+
+	rule := unix.LandlockPathBeneathAttr{Allowed_access: allowed, Parent_fd: int32(fd)}
+	if _, _, errno := unix.Syscall6(unix.SYS_LANDLOCK_ADD_RULE, uintptr(rulesetFd), unix.LANDLOCK_RULE_PATH_BENEATH, uintptr(unsafe.Pointer(&rule)), 0, 0, 0); errno != 0 {
+		return fmt.Errorf("%w: landlock rule for %s: %w", ErrSandboxUnavailable, path, errno)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package scriptsrunner
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// The test binary doubles as SandboxCommand and as the sandboxed program,
+// like jmod does for the scripts it runs.
+func TestMain(m *testing.M) {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case SandboxCommand:
+			var writable []string
+			args := os.Args[2:]
+			for len(args) > 1 && args[0] == "--write" {
+				writable = append(writable, args[1])
+				args = args[2:]
+			}
+			if len(args) > 0 && args[0] == "--" {
+				args = args[1:]
+			}
+			err := SandboxExec(writable, args)
+			fmt.Fprintln(os.Stderr, err)
+			if errors.Is(err, ErrSandboxUnavailable) {
+				os.Exit(sandboxUnavailableExit)
+			}
+			os.Exit(1)
+		case "__write":
+			if err := os.WriteFile(os.Args[2], []byte("x"), 0o644); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			os.Exit(0)
+		case "__traceme":
+			if _, _, errno := unix.RawSyscall(unix.SYS_PTRACE, unix.PTRACE_TRACEME, 0, 0); errno != 0 {
+				fmt.Fprintln(os.Stderr, errno)
+				os.Exit(1)
+			}
+			os.Exit(0)
+		case "__dial":
+			conn, err := net.Dial("tcp", os.Args[2])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			conn.Close()
+			os.Exit(0)
+		}
+	}
+	os.Exit(m.Run())
+}
+
+const sandboxUnavailableExit = 42
+
+// runSandboxed runs the test binary with args in s and reports whether it succeeded.
+func runSandboxed(t *testing.T, s *Sandbox, dir string, args ...string) bool {
+	t.Helper()
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(self, args...)
+	cmd.Dir = dir
+	if err := s.apply(cmd); err != nil {
+		t.Skipf("sandbox unavailable: %s", err)
+	}
+	out, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return true
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == sandboxUnavailableExit:
+		t.Skipf("sandbox unavailable: %s", out)
+	case errors.As(err, &exitErr):
+		return false
+	default:
+		// user namespaces might be disabled
+		t.Skipf("sandbox unavailable: %s", err)
+	}
+	return false
+}
+
+func requireLandlock(t *testing.T) {
+	t.Helper()
+	if _, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, unix.LANDLOCK_CREATE_RULESET_VERSION); errno != 0 {
+		t.Skipf("landlock unavailable: %s", errno)
+	}
+}
+
+func TestSandboxRestrictsWrites(t *testing.T) {
+	requireLandlock(t)
+	base := t.TempDir()
+	pkg := filepath.Join(base, "package")
+	extra := filepath.Join(base, "extra")
+	outside := filepath.Join(base, "outside")
+	for _, dir := range []string{pkg, extra, outside, filepath.Join(base, "tmp")} {
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the temp dir is writable in the sandbox, keep the test directories out of it
+	t.Setenv("TMPDIR", filepath.Join(base, "tmp"))
+
+	s := &Sandbox{Network: true, Write: []string{extra}}
+	if !runSandboxed(t, s, pkg, "__write", filepath.Join(pkg, "ok")) {
+		t.Error("writing to the package directory failed")
+	}
+	if !runSandboxed(t, s, pkg, "__write", filepath.Join(extra, "ok")) {
+		t.Error("writing to an additional writable path failed")
+	}
+	if runSandboxed(t, s, pkg, "__write", filepath.Join(outside, "no")) {
+		t.Error("writing outside the writable paths succeeded")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "no")); err == nil {
+		t.Error("file outside the writable paths was created")
+	}
+}
+
+func TestSandboxBlocksNetwork(t *testing.T) {
+	requireLandlock(t)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("no loopback: %s", err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	dir := t.TempDir()
+	if !runSandboxed(t, &Sandbox{Network: true}, dir, "__dial", l.Addr().String()) {
+		t.Fatal("dial with network access failed")
+	}
+	if runSandboxed(t, &Sandbox{}, dir, "__dial", l.Addr().String()) {
+		t.Error("dial without network access succeeded")
+	}
+}
+
+func TestSandboxDeniesPtrace(t *testing.T) {
+	requireLandlock(t)
+	dir := t.TempDir()
+	if runSandboxed(t, &Sandbox{Network: true}, dir, "__traceme") {
+		t.Error("ptrace in the sandbox succeeded")
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !linux
+
+package scriptsrunner
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// apply only scrubs the environment, filesystem and network restrictions are not supported.
+func (s *Sandbox) apply(cmd *exec.Cmd) error {
+	if s == nil {
+		return nil
+	}
+	warnSandboxUnsupported()
+	cmd.Env = s.scrubEnv(cmd.Env)
+	return nil
+}
+
+func SandboxExec(writable []string, argv []string) error {
+	return fmt.Errorf("%w: only supported on Linux", ErrSandboxUnavailable)
+}
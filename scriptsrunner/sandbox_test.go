@@ -0,0 +1,24 @@
+package scriptsrunner
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestScrubEnv(t *testing.T) {
+	env := []string{
+		"PATH=/usr/bin",
+		"NPM_TOKEN=secret",
+		"GITHUB_TOKEN=secret",
+		"AWS_SECRET_ACCESS_KEY=secret",
+		"npm_config_//registry.npmjs.org/:_authToken=secret",
+		"SSH_AUTH_SOCK=/tmp/agent",
+		"HOME=/home/user",
+	}
+	s := &Sandbox{Env: []string{"GITHUB_TOKEN"}}
+	got := s.scrubEnv(env)
+	want := []string{"PATH=/usr/bin", "GITHUB_TOKEN=secret", "HOME=/home/user"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
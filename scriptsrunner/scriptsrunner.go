@@ -18,18 +18,14 @@ var jsExts = []string{".js", ".mjs", ".cjs", ".ts", ".mts", ".cts"}
 var ErrScriptNotFound = errors.New("script not found")
 
 func Run(packageJsonPath string, scriptName string, args []string, command string) error {
+	return RunSandboxed(packageJsonPath, scriptName, args, command, nil)
+}
+
+// RunSandboxed runs a script like Run but restricted by sandbox.
+// A nil sandbox runs the script unrestricted.
+func RunSandboxed(packageJsonPath string, scriptName string, args []string, command string, sandbox *Sandbox) error {
 	root := filepath.Dir(packageJsonPath)
-	// combine env with the current process env
-	completeEnv := make([]string, 0, len(getDefaultEnv()))
-	completeEnv = append(completeEnv, getDefaultEnv()...)
-	for i, e := range completeEnv {
-		if v, ok := strings.CutPrefix(e, "PATH="); ok {
-			completeEnv[i] = "PATH=" + filepath.Join(root, "node_modules", ".bin") + string(filepath.ListSeparator) + v
-		}
-	}
-	completeEnv = append(completeEnv, "npm_lifecycle_event="+scriptName)
-	completeEnv = append(completeEnv, "npm_package_json="+packageJsonPath)
-	completeEnv = append(completeEnv, "npm_command="+command)
+	completeEnv := scriptEnv(packageJsonPath, scriptName, command)
 
 	pj, err := config.GetPackageJsonForLifecycle(packageJsonPath)
 	if err != nil {
@@ -50,12 +46,28 @@ func Run(packageJsonPath string, scriptName string, args []string, command strin
 	// check if the script is a path to a js file
 	if slices.Contains(jsExts, filepath.Ext(script)) {
 		if _, err := os.Stat(script); err == nil {
-			return runJsScript(root, scriptName, args, completeEnv)
+			return runJsScript(root, scriptName, args, completeEnv, sandbox)
 		}
 	}
 
 	// shell out
-	return runShell(root, script, args, completeEnv)
+	return runShell(root, script, args, completeEnv, sandbox)
+}
+
+// scriptEnv combines the default env with the package specific variables of a script.
+func scriptEnv(packageJsonPath string, scriptName string, command string) []string {
+	root := filepath.Dir(packageJsonPath)
+	env := make([]string, 0, len(getDefaultEnv())+3)
+	env = append(env, getDefaultEnv()...)
+	for i, e := range env {
+		if v, ok := strings.CutPrefix(e, "PATH="); ok {
+			env[i] = "PATH=" + filepath.Join(root, "node_modules", ".bin") + string(filepath.ListSeparator) + v
+		}
+	}
+	env = append(env, "npm_lifecycle_event="+scriptName)
+	env = append(env, "npm_package_json="+packageJsonPath)
+	env = append(env, "npm_command="+command)
+	return env
 }
 
 var defaultJsRunner string
@@ -72,11 +84,14 @@ func getDefaultJsRunner() string {
 	panic("no js runner found")
 }
 
-func runJsScript(root string, scriptName string, args []string, env []string) error {
+func runJsScript(root string, scriptName string, args []string, env []string, sandbox *Sandbox) error {
 	arg := append([]string{scriptName}, args...)
 	cmd := exec.Command(getDefaultJsRunner(), arg...)
 	cmd.Dir = root
 	cmd.Env = env
+	if err := sandbox.apply(cmd); err != nil {
+		return err
+	}
 
 	// Capture output and log errors
 	out, err := cmd.CombinedOutput()
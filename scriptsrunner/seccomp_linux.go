@@ -0,0 +1,96 @@
+package scriptsrunner
+
+import (
+	"fmt"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// seccompArch is the audit architecture of the syscalls the seccomp filter is written for
+var seccompArch = map[string]uint32{
+	"386":     unix.AUDIT_ARCH_I386,
+	"amd64":   unix.AUDIT_ARCH_X86_64,
+	"arm64":   unix.AUDIT_ARCH_AARCH64,
+	"riscv64": unix.AUDIT_ARCH_RISCV64,
+}
+
+// seccompDenied are the syscalls a sandboxed script gets EPERM for.
+// They inspect or modify other processes, change mounts and namespaces
+// or expose kernel interfaces an install script has no use for.
+var seccompDenied = []uintptr{
+	unix.SYS_PTRACE,
+	unix.SYS_PROCESS_VM_READV,
+	unix.SYS_PROCESS_VM_WRITEV,
+	unix.SYS_MOUNT,
+	unix.SYS_UMOUNT2,
+	unix.SYS_PIVOT_ROOT,
+	unix.SYS_UNSHARE,
+	unix.SYS_SETNS,
+	unix.SYS_BPF,
+	unix.SYS_PERF_EVENT_OPEN,
+	unix.SYS_USERFAULTFD,
+	unix.SYS_KEYCTL,
+	unix.SYS_ADD_KEY,
+	unix.SYS_REQUEST_KEY,
+	unix.SYS_OPEN_BY_HANDLE_AT,
+	unix.SYS_INIT_MODULE,
+	unix.SYS_FINIT_MODULE,
+	unix.SYS_DELETE_MODULE,
+	unix.SYS_KEXEC_LOAD,
+	unix.SYS_REBOOT,
+	unix.SYS_SWAPON,
+	unix.SYS_SWAPOFF,
+}
+
+// x32SyscallBit marks syscalls of the x32 ABI, which share the x86_64 audit architecture
+const x32SyscallBit = 0x40000000
+
+// seccompFilter builds the filter program for arch.
+// Syscalls of other architectures kill the process, denied syscalls fail with EPERM, everything else is allowed.
+func seccompFilter(arch uint32) []unix.SockFilter {
+	stmt := func(code uint16, k uint32) unix.SockFilter {
+		return unix.SockFilter{Code: code, K: k}
+	}
+	jump := func(code uint16, k uint32, jt, jf uint8) unix.SockFilter {
+		return unix.SockFilter{Code: code, Jt: jt, Jf: jf, K: k}
+	}
+	// offsets in struct seccomp_data
+	const nrOffset, archOffset = 0, 4
+	deny := unix.SECCOMP_RET_ERRNO | uint32(unix.EPERM)
+
+	filter := []unix.SockFilter{
+		stmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, archOffset),
+		jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, arch, 1, 0),
+		stmt(unix.BPF_RET|unix.BPF_K, unix.SECCOMP_RET_KILL_PROCESS),
+		stmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, nrOffset),
+	}
+	if arch == unix.AUDIT_ARCH_X86_64 {
+		filter = append(filter,
+			jump(unix.BPF_JMP|unix.BPF_JGE|unix.BPF_K, x32SyscallBit, 0, 1),
+			stmt(unix.BPF_RET|unix.BPF_K, deny),
+		)
+	}
+	for _, nr := range seccompDenied {
+		filter = append(filter,
+			jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, uint32(nr), 0, 1),
+			stmt(unix.BPF_RET|unix.BPF_K, deny),
+		)
+	}
+	return append(filter, stmt(unix.BPF_RET|unix.BPF_K, unix.SECCOMP_RET_ALLOW))
+}
+
+// applySeccomp installs the seccomp filter on the calling thread, no_new_privs must be set already.
+func applySeccomp() error {
+	arch, ok := seccompArch[runtime.GOARCH]
+	if !ok {
+		return fmt.Errorf("%w: seccomp filter not available on %s", ErrSandboxUnavailable, runtime.GOARCH)
+	}
+	filter := seccompFilter(arch)
+	prog := unix.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+	if err := unix.Prctl(unix.PR_SET_SECCOMP, unix.SECCOMP_MODE_FILTER, uintptr(unsafe.Pointer(&prog)), 0, 0); err != nil {
+		return fmt.Errorf("%w: seccomp: %w", ErrSandboxUnavailable, err)
+	}
+	return nil
+}
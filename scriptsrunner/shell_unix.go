@@ -31,7 +31,7 @@ func getDefaultShell() string {
 	return defaultShell
 }
 
-func runShell(root, script string, args []string, env []string) error {
+func runShell(root, script string, args []string, env []string, sandbox *Sandbox) error {
 	sh := getDefaultShell()
 
 	// Run: sh -c '<script> "$@"' _ <args...>
@@ -41,6 +41,9 @@ func runShell(root, script string, args []string, env []string) error {
 	cmd := exec.Command(sh, argv...)
 	cmd.Dir = root
 	cmd.Env = env
+	if err := sandbox.apply(cmd); err != nil {
+		return err
+	}
 
 	// Capture output and log errors
 	out, err := cmd.CombinedOutput()
@@ -9,13 +9,13 @@ import (
 	"github.com/tsukinoko-kun/jmod/logger"
 )
 
-type shellRunner func(root, script string, args []string, env []string) error
+type shellRunner func(root, script string, args []string, env []string, sandbox *Sandbox) error
 
 var defaultRunner shellRunner
 
 func pickRunner() (shellRunner, error) {
 	if p, err := exec.LookPath("pwsh"); err == nil {
-		return func(root, script string, args []string, env []string) error {
+		return func(root, script string, args []string, env []string, sandbox *Sandbox) error {
 			// pwsh -Command '& { <script> } @args; exit $LASTEXITCODE' <args...>
 			argv := []string{
 				"-NoProfile",
@@ -28,6 +28,9 @@ func pickRunner() (shellRunner, error) {
 			cmd := exec.Command(p, argv...)
 			cmd.Dir = root
 			cmd.Env = env
+			if err := sandbox.apply(cmd); err != nil {
+				return err
+			}
 
 			// Capture output and log errors
 			out, err := cmd.CombinedOutput()
@@ -42,7 +45,7 @@ func pickRunner() (shellRunner, error) {
 	}
 
 	if p, err := exec.LookPath("powershell"); err == nil {
-		return func(root, script string, args []string, env []string) error {
+		return func(root, script string, args []string, env []string, sandbox *Sandbox) error {
 			// powershell -Command '& { <script> } @args; exit $LASTEXITCODE' <args...>
 			argv := []string{
 				"-NoProfile",
@@ -55,6 +58,9 @@ func pickRunner() (shellRunner, error) {
 			cmd := exec.Command(p, argv...)
 			cmd.Dir = root
 			cmd.Env = env
+			if err := sandbox.apply(cmd); err != nil {
+				return err
+			}
 
 			// Capture output and log errors
 			out, err := cmd.CombinedOutput()
@@ -69,7 +75,7 @@ func pickRunner() (shellRunner, error) {
 	}
 
 	if p, err := exec.LookPath("cmd"); err == nil {
-		return func(root, script string, args []string, env []string) error {
+		return func(root, script string, args []string, env []string, sandbox *Sandbox) error {
 			// For cmd there’s no "$@" equivalent on the command line.
 			// Use a temporary .cmd wrapper and %* inside it.
 			f, err := os.CreateTemp("", "jmod-run-*.cmd")
@@ -91,6 +97,9 @@ func pickRunner() (shellRunner, error) {
 			cmd := exec.Command(p, argv...)
 			cmd.Dir = root
 			cmd.Env = env
+			if err := sandbox.apply(cmd); err != nil {
+				return err
+			}
 
 			// Capture output and log errors
 			out, err := cmd.CombinedOutput()
@@ -107,7 +116,7 @@ func pickRunner() (shellRunner, error) {
 	return nil, errors.New("no shell found")
 }
 
-func runShell(root string, script string, args []string, env []string) error {
+func runShell(root string, script string, args []string, env []string, sandbox *Sandbox) error {
 	if defaultRunner == nil {
 		r, err := pickRunner()
 		if err != nil {
@@ -115,5 +124,5 @@ func runShell(root string, script string, args []string, env []string) error {
 		}
 		defaultRunner = r
 	}
-	return defaultRunner(root, script, args, env)
+	return defaultRunner(root, script, args, env, sandbox)
 }
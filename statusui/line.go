@@ -0,0 +1,136 @@
+package statusui
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// lineReporter prints a line whenever a step of a status key completes.
+// A step is completed when the key moves on to another step, succeeds, fails or is cleared.
+type lineReporter struct {
+	mu    sync.Mutex
+	w     io.Writer
+	steps map[string]*lineStep
+	now   func() time.Time
+}
+
+type lineStep struct {
+	text string
+	// bytes is the progress of a ProgressStatus step
+	bytes int64
+	start time.Time
+	// first is the start of the first step of the key
+	first time.Time
+}
+
+func newLineReporter(w io.Writer) *lineReporter {
+	return &lineReporter{w: w, steps: make(map[string]*lineStep), now: time.Now}
+}
+
+func (l *lineReporter) set(key string, status Status) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	step, ok := l.steps[key]
+	first := now
+	if ok {
+		first = step.first
+	}
+
+	switch status := status.(type) {
+	case GaugeStatus:
+		return
+	case SuccessStatus:
+		// the completed step describes the work better than the generic success message
+		if ok {
+			l.finish(step, now)
+		} else {
+			fmt.Fprintln(l.w, status.Render())
+		}
+		delete(l.steps, key)
+		return
+	case ErrorStatus:
+		fmt.Fprintf(l.w, "%s (%s)\n", status.Render(), formatDuration(now.Sub(first)))
+		delete(l.steps, key)
+		return
+	case PendingStatus:
+		// waiting is not a step of its own, it counts towards the next one
+		if ok {
+			l.finish(step, now)
+		}
+		l.steps[key] = &lineStep{start: l.startOf(step, ok, now), first: first}
+		return
+	case ProgressStatus:
+		if ok && step.text == status.Label {
+			step.bytes = status.Current
+			return
+		}
+		if ok {
+			l.finish(step, now)
+		}
+		l.steps[key] = &lineStep{text: status.Label, bytes: status.Current, start: l.startOf(step, ok, now), first: first}
+	default:
+		text := status.Render()
+		if ok && step.text == text {
+			return
+		}
+		if ok {
+			l.finish(step, now)
+		}
+		l.steps[key] = &lineStep{text: text, start: l.startOf(step, ok, now), first: first}
+	}
+}
+
+// startOf keeps the start of a pending step for the step that follows it.
+func (l *lineReporter) startOf(step *lineStep, ok bool, now time.Time) time.Time {
+	if ok && step.text == "" {
+		return step.start
+	}
+	return now
+}
+
+func (l *lineReporter) clear(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if step, ok := l.steps[key]; ok {
+		l.finish(step, l.now())
+		delete(l.steps, key)
+	}
+}
+
+// finish prints a completed step, l.mu must be held.
+func (l *lineReporter) finish(step *lineStep, now time.Time) {
+	if step.text == "" {
+		return
+	}
+	if step.bytes > 0 {
+		fmt.Fprintf(l.w, "%s %s (%s)\n", step.text, formatBytes(step.bytes), formatDuration(now.Sub(step.start)))
+		return
+	}
+	fmt.Fprintf(l.w, "%s (%s)\n", step.text, formatDuration(now.Sub(step.start)))
+}
+
+func (l *lineReporter) log(message string, level logLevel) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	fmt.Fprintln(l.w, styleLog(message, level))
+}
+
+func (l *lineReporter) stop() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	clear(l.steps)
+}
+
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
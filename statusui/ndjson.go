@@ -0,0 +1,76 @@
+package statusui
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// ndjsonEvent is a line written by the ndjson reporter.
+type ndjsonEvent struct {
+	Time time.Time `json:"time"`
+	// Event is "set", "clear" or "log"
+	Event string `json:"event"`
+	Key   string `json:"key,omitempty"`
+	// Status is the type of a set status: text, progress, pending, gauge, success or error
+	Status  string `json:"status,omitempty"`
+	Text    string `json:"text,omitempty"`
+	Current int64  `json:"current,omitempty"`
+	Total   int64  `json:"total,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Level   string `json:"level,omitempty"`
+}
+
+// ndjsonReporter writes every Set, Clear and Log call as a JSON object per line.
+type ndjsonReporter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	now func() time.Time
+}
+
+func newNdjsonReporter(w io.Writer) *ndjsonReporter {
+	return &ndjsonReporter{enc: json.NewEncoder(w), now: time.Now}
+}
+
+func (n *ndjsonReporter) set(key string, status Status) {
+	ev := ndjsonEvent{Event: "set", Key: key}
+	switch status := status.(type) {
+	case TextStatus:
+		ev.Status, ev.Text = "text", status.Text
+	case ProgressStatus:
+		ev.Status, ev.Text, ev.Current, ev.Total = "progress", status.Label, status.Current, status.Total
+	case PendingStatus:
+		ev.Status, ev.Text = "pending", status.Text
+	case GaugeStatus:
+		ev.Status, ev.Text = "gauge", status.Text
+	case SuccessStatus:
+		ev.Status, ev.Text = "success", status.Message
+	case ErrorStatus:
+		ev.Status, ev.Text = "error", status.Message
+		if status.Err != nil {
+			ev.Error = status.Err.Error()
+		}
+	default:
+		ev.Status, ev.Text = "text", status.Render()
+	}
+	n.write(ev)
+}
+
+func (n *ndjsonReporter) clear(key string) {
+	n.write(ndjsonEvent{Event: "clear", Key: key})
+}
+
+func (n *ndjsonReporter) log(message string, level logLevel) {
+	n.write(ndjsonEvent{Event: "log", Text: message, Level: level.String()})
+}
+
+func (n *ndjsonReporter) stop() {}
+
+func (n *ndjsonReporter) write(ev ndjsonEvent) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	ev.Time = n.now()
+	_ = n.enc.Encode(ev)
+}
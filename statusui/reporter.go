@@ -0,0 +1,113 @@
+package statusui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Reporter selects how statuses and logs are written.
+type Reporter string
+
+const (
+	// ReporterAuto uses the TUI on terminals and the line reporter otherwise
+	ReporterAuto Reporter = "auto"
+	// ReporterTui renders the live status lines with bubbletea
+	ReporterTui Reporter = "tui"
+	// ReporterLine prints one line per completed step, suited for CI logs and pipes
+	ReporterLine Reporter = "line"
+	// ReporterNdjson prints one JSON event per line for other programs
+	ReporterNdjson Reporter = "ndjson"
+)
+
+// Mode is the reporter used by Start.
+var Mode = ReporterAuto
+
+// ParseReporter validates a reporter name, an empty name is ReporterAuto.
+func ParseReporter(s string) (Reporter, error) {
+	switch r := Reporter(strings.ToLower(s)); r {
+	case "":
+		return ReporterAuto, nil
+	case ReporterAuto, ReporterTui, ReporterLine, ReporterNdjson:
+		return r, nil
+	default:
+		return "", fmt.Errorf("unknown reporter %q, expected auto, tui, line or ndjson", s)
+	}
+}
+
+func (r Reporter) resolve() Reporter {
+	if r != ReporterAuto && r != "" {
+		return r
+	}
+	if !isTerminal(os.Stderr) || os.Getenv("TERM") == "dumb" {
+		return ReporterLine
+	}
+	return ReporterTui
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// reporter receives the Set, Clear and Log calls while statusui is running
+type reporter interface {
+	set(key string, status Status)
+	clear(key string)
+	log(message string, level logLevel)
+	stop()
+}
+
+type tuiReporter struct {
+	program *tea.Program
+	// printAfterClose keeps the logs, the last frame of the TUI is cleared on exit
+	printAfterClose strings.Builder
+}
+
+func newTuiReporter() *tuiReporter {
+	// Create program for inline rendering (not alternate screen)
+	// This will render updates inline in the terminal output
+	program := tea.NewProgram(
+		initialModel(),
+		tea.WithOutput(os.Stderr),
+		tea.WithoutSignalHandler(), // Don't intercept signals
+		tea.WithInput(nil),         // No input needed
+		// tea.WithFPS(10),            // Limit to 10 FPS to avoid rapid re-renders
+	)
+
+	// Start program in background
+	go func() {
+		if _, err := program.Run(); err != nil {
+			// Log error but don't crash
+			fmt.Fprintf(os.Stderr, "Error running TUI: %v\n", err)
+		}
+	}()
+
+	return &tuiReporter{program: program}
+}
+
+func (t *tuiReporter) set(key string, status Status) {
+	t.program.Send(setStatusMsg{key: key, status: status})
+}
+
+func (t *tuiReporter) clear(key string) {
+	t.program.Send(clearStatusMsg{key: key})
+}
+
+// log is called with programLock held
+func (t *tuiReporter) log(message string, level logLevel) {
+	message = styleLog(message, level)
+	t.printAfterClose.WriteString(message)
+	t.printAfterClose.WriteRune('\n')
+	t.program.Send(logMsg{message: message})
+}
+
+// stop is called with programLock held
+func (t *tuiReporter) stop() {
+	t.program.Quit()
+	time.Sleep(100 * time.Millisecond)
+	fmt.Print(t.printAfterClose.String())
+}
@@ -0,0 +1,72 @@
+package statusui
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLineReporter(t *testing.T) {
+	var out strings.Builder
+	l := newLineReporter(&out)
+	now := time.Unix(0, 0)
+	l.now = func() time.Time { return now }
+
+	l.set("network", GaugeStatus{Text: "🌐 1 active, 0 queued requests"})
+	l.set("react", PendingStatus{Text: "🕓 Queued react@18.3.1"})
+	now = now.Add(100 * time.Millisecond)
+	l.set("react", ProgressStatus{Label: "Downloading react@18.3.1", Current: 512, Total: 2048})
+	now = now.Add(100 * time.Millisecond)
+	l.set("react", ProgressStatus{Label: "Downloading react@18.3.1", Current: 2048, Total: 2048})
+	l.set("react", TextStatus{Text: "Extracting react@18.3.1"})
+	now = now.Add(50 * time.Millisecond)
+	l.set("react", SuccessStatus{Message: "Installed react@18.3.1"})
+	l.clear("react")
+	l.set("esbuild", TextStatus{Text: "Running postinstall script for esbuild"})
+	now = now.Add(time.Second)
+	l.set("esbuild", ErrorStatus{Message: "postinstall failed", Err: errors.New("exit status 1")})
+
+	want := strings.Join([]string{
+		"Downloading react@18.3.1 2.0 KB (200ms)",
+		"Extracting react@18.3.1 (50ms)",
+		"❌ postinstall failed: exit status 1 (1s)",
+		"",
+	}, "\n")
+	if out.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestNdjsonReporter(t *testing.T) {
+	var out strings.Builder
+	n := newNdjsonReporter(&out)
+
+	n.set("react", ProgressStatus{Label: "Downloading react", Current: 1, Total: 2})
+	n.clear("react")
+	n.log("done", LogLevelWarn)
+
+	var events []ndjsonEvent
+	scanner := bufio.NewScanner(strings.NewReader(out.String()))
+	for scanner.Scan() {
+		var ev ndjsonEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+		events = append(events, ev)
+	}
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3", len(events))
+	}
+	if ev := events[0]; ev.Event != "set" || ev.Status != "progress" || ev.Current != 1 || ev.Total != 2 {
+		t.Errorf("unexpected set event %+v", ev)
+	}
+	if ev := events[1]; ev.Event != "clear" || ev.Key != "react" {
+		t.Errorf("unexpected clear event %+v", ev)
+	}
+	if ev := events[2]; ev.Event != "log" || ev.Level != "warn" || ev.Text != "done" {
+		t.Errorf("unexpected log event %+v", ev)
+	}
+}
@@ -48,6 +48,26 @@ func (p ProgressStatus) Render() string {
 		p.Label, bar, percentage, formatBytes(p.Current), formatBytes(p.Total))
 }
 
+// PendingStatus displays a task waiting for something, like a queued request or a retry delay.
+// Line based reporters don't report it as a completed step.
+type PendingStatus struct {
+	Text string
+}
+
+func (p PendingStatus) Render() string {
+	return p.Text
+}
+
+// GaugeStatus displays a value that changes continuously, like the number of active requests.
+// Line based reporters don't print it.
+type GaugeStatus struct {
+	Text string
+}
+
+func (g GaugeStatus) Render() string {
+	return g.Text
+}
+
 // ErrorStatus displays an error message
 type ErrorStatus struct {
 	Message string
@@ -7,7 +7,6 @@ import (
 	"slices"
 	"strings"
 	"sync"
-	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -16,7 +15,7 @@ import (
 
 // Global state
 var (
-	program     *tea.Program
+	active      reporter
 	programLock sync.Mutex
 )
 
@@ -113,75 +112,63 @@ func (m model) View() string {
 	return output.String()
 }
 
-// Start initializes the Bubbletea program
+// Start initializes the reporter selected by Mode
 func Start() error {
 	programLock.Lock()
 	defer programLock.Unlock()
 
-	if program != nil {
+	if active != nil {
 		return fmt.Errorf("TUI already running")
 	}
 
-	// Create program for inline rendering (not alternate screen)
-	// This will render updates inline in the terminal output
-	program = tea.NewProgram(
-		initialModel(),
-		tea.WithOutput(os.Stderr),
-		tea.WithoutSignalHandler(), // Don't intercept signals
-		tea.WithInput(nil),         // No input needed
-		// tea.WithFPS(10),            // Limit to 10 FPS to avoid rapid re-renders
-	)
-
-	// Start program in background
-	go func() {
-		if _, err := program.Run(); err != nil {
-			// Log error but don't crash
-			fmt.Fprintf(os.Stderr, "Error running TUI: %v\n", err)
-		}
-	}()
+	switch Mode.resolve() {
+	case ReporterLine:
+		active = newLineReporter(os.Stderr)
+	case ReporterNdjson:
+		active = newNdjsonReporter(os.Stderr)
+	default:
+		active = newTuiReporter()
+	}
 
 	return nil
 }
 
-// Stop terminates the Bubbletea program
+// Stop terminates the reporter
 func Stop() {
 	programLock.Lock()
 	defer programLock.Unlock()
 
-	if program != nil {
-		program.Quit()
-		program = nil
-		time.Sleep(100 * time.Millisecond)
-		fmt.Print(printAfterTuiClose.String())
-		printAfterTuiClose.Reset()
+	if active != nil {
+		active.stop()
+		active = nil
 	}
 }
 
 // Set updates or creates a status for the given key
 func Set(key string, status Status) bool {
 	programLock.Lock()
-	p := program
+	r := active
 	programLock.Unlock()
 
-	if p == nil {
+	if r == nil {
 		return false
 	}
 
-	p.Send(setStatusMsg{key: key, status: status})
+	r.set(key, status)
 	return true
 }
 
 // Clear removes a status for the given key
 func Clear(key string) {
 	programLock.Lock()
-	p := program
+	r := active
 	programLock.Unlock()
 
-	if p == nil {
+	if r == nil {
 		return
 	}
 
-	p.Send(clearStatusMsg{key: key})
+	r.clear(key)
 }
 
 type logLevel uint8
@@ -192,6 +179,17 @@ const (
 	LogLevelError
 )
 
+func (l logLevel) String() string {
+	switch l {
+	case LogLevelWarn:
+		return "warn"
+	case LogLevelError:
+		return "error"
+	default:
+		return "info"
+	}
+}
+
 var (
 	logInfoStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("4"))
@@ -201,35 +199,34 @@ var (
 			Foreground(lipgloss.Color("1"))
 )
 
-var printAfterTuiClose = strings.Builder{}
-
-// Log sends a log message to the TUI
-func Log(message string, level logLevel) {
-	programLock.Lock()
-	p := program
-	defer programLock.Unlock()
-
-	var style lipgloss.Style
+func styleLog(message string, level logLevel) string {
 	switch level {
-	case LogLevelInfo:
-		style = logInfoStyle
 	case LogLevelWarn:
-		style = logWarnStyle
+		return logWarnStyle.Render(message)
 	case LogLevelError:
-		style = logErrorStyle
+		return logErrorStyle.Render(message)
+	default:
+		return logInfoStyle.Render(message)
 	}
-	message = style.Render(message)
+}
 
-	if p == nil {
-		// If TUI not running, print directly
-		fmt.Fprintln(os.Stderr, message)
+// Log sends a log message to the reporter
+func Log(message string, level logLevel) {
+	programLock.Lock()
+	r := active
+	defer programLock.Unlock()
+
+	if r == nil {
+		// If no reporter is running, print directly
+		if Mode == ReporterNdjson {
+			newNdjsonReporter(os.Stderr).log(message, level)
+		} else {
+			fmt.Fprintln(os.Stderr, styleLog(message, level))
+		}
 		return
 	}
 
-	printAfterTuiClose.WriteString(message)
-	printAfterTuiClose.WriteRune('\n')
-
-	p.Send(logMsg{message: message})
+	r.log(message, level)
 }
 
 // LogWriter is an io.Writer that sends output to the TUI
@@ -0,0 +1,222 @@
+package tidy
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/tsukinoko-kun/jmod/logger"
+)
+
+// aliases resolves the imports a module maps to its own files
+// through compilerOptions.paths and baseUrl of its tsconfig.json or jsconfig.json.
+type aliases struct {
+	// baseUrl is the absolute directory bare imports are also looked up in, empty if not configured
+	baseUrl string
+	paths   []pathAlias
+}
+
+// pathAlias is a compilerOptions.paths entry like "@/*": ["./src/*"].
+type pathAlias struct {
+	prefix   string
+	suffix   string
+	wildcard bool
+	// targets are absolute paths that may contain one *
+	targets []string
+}
+
+type tsconfig struct {
+	Extends         json.RawMessage `json:"extends"`
+	CompilerOptions struct {
+		BaseUrl *string              `json:"baseUrl"`
+		Paths   *map[string][]string `json:"paths"`
+	} `json:"compilerOptions"`
+}
+
+// loadAliases reads the tsconfig.json, or the jsconfig.json, of the module at root.
+func loadAliases(root string) aliases {
+	for _, name := range []string{"tsconfig.json", "jsconfig.json"} {
+		file := filepath.Join(root, name)
+		if _, err := os.Stat(file); err != nil {
+			continue
+		}
+		var a aliases
+		var pathsDir string
+		var paths map[string][]string
+		if err := readTsconfig(file, &a.baseUrl, &paths, &pathsDir, map[string]bool{}); err != nil {
+			logger.Printf("failed to read %s: %s", file, err)
+		}
+		// paths are relative to baseUrl, or to the config declaring them without baseUrl
+		if a.baseUrl != "" {
+			pathsDir = a.baseUrl
+		}
+		for pattern, targets := range paths {
+			alias := pathAlias{prefix: pattern}
+			if before, after, ok := strings.Cut(pattern, "*"); ok {
+				alias = pathAlias{prefix: before, suffix: after, wildcard: true}
+			}
+			for _, target := range targets {
+				alias.targets = append(alias.targets, filepath.Join(pathsDir, target))
+			}
+			a.paths = append(a.paths, alias)
+		}
+		return a
+	}
+	return aliases{}
+}
+
+// readTsconfig applies the config at file on top of the configs it extends.
+func readTsconfig(file string, baseUrl *string, paths *map[string][]string, pathsDir *string, seen map[string]bool) error {
+	if seen[file] {
+		return nil
+	}
+	seen[file] = true
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	var config tsconfig
+	if err := json.Unmarshal(stripJSONC(data), &config); err != nil {
+		return err
+	}
+	dir := filepath.Dir(file)
+
+	var extends []string
+	if len(config.Extends) != 0 {
+		var single string
+		if err := json.Unmarshal(config.Extends, &single); err == nil {
+			extends = []string{single}
+		} else if err := json.Unmarshal(config.Extends, &extends); err != nil {
+			return err
+		}
+	}
+	for _, extend := range extends {
+		if base, ok := resolveTsconfig(dir, extend); ok {
+			if err := readTsconfig(base, baseUrl, paths, pathsDir, seen); err != nil {
+				logger.Printf("failed to read %s: %s", base, err)
+			}
+		}
+	}
+
+	if config.CompilerOptions.BaseUrl != nil {
+		*baseUrl = filepath.Join(dir, *config.CompilerOptions.BaseUrl)
+	}
+	if config.CompilerOptions.Paths != nil {
+		*paths = *config.CompilerOptions.Paths
+		*pathsDir = dir
+	}
+	return nil
+}
+
+// resolveTsconfig finds the config an extends entry refers to,
+// a path relative to dir or a package in a node_modules directory.
+func resolveTsconfig(dir string, extend string) (string, bool) {
+	var candidates []string
+	if strings.HasPrefix(extend, ".") || filepath.IsAbs(extend) {
+		file := extend
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, extend)
+		}
+		candidates = append(candidates, file, file+".json")
+	} else {
+		for d := dir; ; d = filepath.Dir(d) {
+			file := filepath.Join(d, "node_modules", filepath.FromSlash(extend))
+			candidates = append(candidates, file, file+".json", filepath.Join(file, "tsconfig.json"))
+			if filepath.Dir(d) == d {
+				break
+			}
+		}
+	}
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// isLocal reports whether spec is resolved to a file of the module by the paths or the baseUrl.
+// The catch-all pattern "*" and the baseUrl only count if the file exists,
+// otherwise every package would be local.
+func (a aliases) isLocal(spec string) bool {
+	for _, alias := range a.paths {
+		if !alias.wildcard {
+			if spec == alias.prefix {
+				return true
+			}
+			continue
+		}
+		if !strings.HasPrefix(spec, alias.prefix) || !strings.HasSuffix(spec, alias.suffix) || len(spec) < len(alias.prefix)+len(alias.suffix) {
+			continue
+		}
+		if alias.prefix != "" || alias.suffix != "" {
+			return true
+		}
+		match := spec[len(alias.prefix) : len(spec)-len(alias.suffix)]
+		for _, target := range alias.targets {
+			if sourceExists(strings.Replace(target, "*", filepath.FromSlash(match), 1)) {
+				return true
+			}
+		}
+	}
+	return a.baseUrl != "" && sourceExists(filepath.Join(a.baseUrl, filepath.FromSlash(spec)))
+}
+
+var sourceExtensions = []string{".ts", ".tsx", ".d.ts", ".mts", ".cts", ".js", ".jsx", ".mjs", ".cjs", ".json"}
+
+// sourceExists reports whether an import of path resolves to a file or directory.
+func sourceExists(path string) bool {
+	if _, err := os.Stat(path); err == nil {
+		return true
+	}
+	for _, ext := range sourceExtensions {
+		if _, err := os.Stat(path + ext); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+var trailingComma = regexp.MustCompile(`,(\s*[}\]])`)
+
+// stripJSONC removes the comments and trailing commas tsconfig files may contain.
+func stripJSONC(data []byte) []byte {
+	var out bytes.Buffer
+	out.Grow(len(data))
+	inString := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if inString {
+			out.WriteByte(c)
+			if c == '\\' && i+1 < len(data) {
+				i++
+				out.WriteByte(data[i])
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+		switch {
+		case c == '"':
+			inString = true
+			out.WriteByte(c)
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			out.WriteByte('\n')
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			i += 2
+			for i+1 < len(data) && (data[i] != '*' || data[i+1] != '/') {
+				i++
+			}
+			i++
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return trailingComma.ReplaceAll(out.Bytes(), []byte("$1"))
+}
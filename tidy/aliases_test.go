@@ -0,0 +1,46 @@
+package tidy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAliases(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("configs/tsconfig.base.json", `{
+		// shared aliases
+		"compilerOptions": {
+			"paths": {
+				"@app/*": ["../src/app/*"], /* relative to this file */
+				"~utils": ["../src/utils/index.ts"],
+			},
+		},
+	}`)
+	write("tsconfig.json", `{"extends": "./configs/tsconfig.base", "compilerOptions": {"baseUrl": "src"}}`)
+	write("src/components/button.tsx", "")
+
+	a := loadAliases(root)
+	for spec, want := range map[string]bool{
+		"@app/routes":       true,
+		"~utils":            true,
+		"components/button": true,
+		"components/card":   false,
+		"react":             false,
+		"@apple/pie":        false,
+	} {
+		if got := a.isLocal(spec); got != want {
+			t.Errorf("isLocal(%s) = %v, want %v", spec, got, want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package tidy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tsukinoko-kun/jmod/config"
+)
+
+// nodeBuiltins are the modules of Node that can be imported without the node: prefix.
+// Modules like node:test or node:sqlite are left out, they only exist with the prefix
+// and the plain names are npm packages.
+var nodeBuiltins = map[string]bool{
+	"assert": true, "assert/strict": true, "async_hooks": true, "buffer": true,
+	"child_process": true, "cluster": true, "console": true, "constants": true,
+	"crypto": true, "dgram": true, "diagnostics_channel": true, "dns": true,
+	"dns/promises": true, "domain": true, "events": true, "fs": true,
+	"fs/promises": true, "http": true, "http2": true, "https": true,
+	"inspector": true, "inspector/promises": true, "module": true, "net": true,
+	"os": true, "path": true, "path/posix": true, "path/win32": true,
+	"perf_hooks": true, "process": true, "punycode": true, "querystring": true,
+	"readline": true, "readline/promises": true, "repl": true, "stream": true,
+	"stream/consumers": true, "stream/promises": true, "stream/web": true,
+	"string_decoder": true, "sys": true, "timers": true, "timers/promises": true,
+	"tls": true, "trace_events": true, "tty": true, "url": true, "util": true,
+	"util/types": true, "v8": true, "vm": true, "wasi": true,
+	"worker_threads": true, "zlib": true,
+}
+
+// builtins decides which import specifiers refer to modules of the runtime instead of packages.
+type builtins struct {
+	bun bool
+}
+
+// detectBuiltins checks which runtimes besides Node the project at root targets.
+// A project targets Bun if it has a Bun config or lockfile or a module declares the Bun types.
+func detectBuiltins(root string, mods []*module) builtins {
+	var b builtins
+	for _, name := range []string{"bunfig.toml", "bun.lock", "bun.lockb"} {
+		if _, err := os.Stat(filepath.Join(root, name)); err == nil {
+			b.bun = true
+		}
+	}
+	for _, m := range mods {
+		mod := m.doc.TypedData
+		for _, deps := range []map[string]string{mod.NpmDependencies, mod.NpmDevDependencies} {
+			if _, ok := deps["@types/bun"]; ok {
+				b.bun = true
+			}
+			if _, ok := deps["bun-types"]; ok {
+				b.bun = true
+			}
+		}
+	}
+	return b
+}
+
+// isBuiltin reports whether spec is a module of the runtime, like fs, node:fs, bun:test or a Deno jsr: or https: import.
+func (b builtins) isBuiltin(spec string) bool {
+	// prefixed specifiers are resolved by the runtime, never by node_modules
+	if scheme, _, ok := strings.Cut(spec, ":"); ok && !strings.Contains(scheme, "/") {
+		return true
+	}
+	if nodeBuiltins[spec] {
+		return true
+	}
+	return b.bun && (spec == "bun" || strings.HasPrefix(spec, "bun/"))
+}
+
+// importsBuiltin reports whether spec imports a module of the runtime.
+// Unprefixed names like buffer or util refer to the npm polyfill if the module declares it,
+// bundlers resolve those from node_modules instead of the runtime.
+func (m *module) importsBuiltin(b builtins, spec string) bool {
+	if !b.isBuiltin(spec) {
+		return false
+	}
+	if strings.Contains(spec, ":") {
+		return true
+	}
+	mod := m.doc.TypedData
+	name := transformForNpm(spec)
+	_, dep := mod.NpmDependencies[name]
+	_, dev := mod.NpmDevDependencies[name]
+	return !dep && !dev
+}
+
+// ignoredPackages are never added by tidy in addition to the configured ignore list.
+// server-only is resolved by the bundler of Next.js.
+var ignoredPackages = []string{
+	"server-only",
+}
+
+// isIgnored reports whether tidy must not add the package name.
+func isIgnored(settings *config.TidySettings, name string) bool {
+	if isKept(ignoredPackages, name) {
+		return true
+	}
+	return settings != nil && isKept(settings.Ignore, name)
+}
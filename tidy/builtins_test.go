@@ -0,0 +1,51 @@
+package tidy
+
+import (
+	"testing"
+
+	"github.com/tsukinoko-kun/jmod/config"
+	json "github.com/tsukinoko-kun/jsonedit"
+)
+
+func TestIsBuiltin(t *testing.T) {
+	for _, tc := range []struct {
+		b    builtins
+		spec string
+		want bool
+	}{
+		{builtins{}, "fs", true},
+		{builtins{}, "fs/promises", true},
+		{builtins{}, "node:test", true},
+		{builtins{}, "test", false},
+		{builtins{}, "react", false},
+		{builtins{}, "bun:test", true},
+		{builtins{}, "bun", false},
+		{builtins{bun: true}, "bun", true},
+		{builtins{}, "jsr:@std/path", true},
+		{builtins{}, "@scope/pkg:thing", false},
+	} {
+		if got := tc.b.isBuiltin(tc.spec); got != tc.want {
+			t.Errorf("isBuiltin(%s) with bun=%v = %v, want %v", tc.spec, tc.b.bun, got, tc.want)
+		}
+	}
+}
+
+func TestImportsBuiltin(t *testing.T) {
+	m := &module{doc: &json.Document[*config.Mod]{TypedData: &config.Mod{
+		NpmDependencies:    map[string]string{"buffer": "^6.0.3"},
+		NpmDevDependencies: map[string]string{"util": "^0.12.5"},
+	}}}
+	for spec, want := range map[string]bool{
+		"buffer":      false,
+		"util":        false,
+		"util/":       false,
+		"events":      true,
+		"node:buffer": true,
+		"fs":          true,
+		"react":       false,
+	} {
+		if got := m.importsBuiltin(builtins{}, spec); got != want {
+			t.Errorf("importsBuiltin(%s) = %v, want %v", spec, got, want)
+		}
+	}
+}
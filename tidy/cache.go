@@ -0,0 +1,138 @@
+package tidy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+// parseWorkers is the number of files parsed at once.
+var parseWorkers = runtime.NumCPU()
+
+// parseCacheVersion invalidates the cache when the parsers find different imports.
+const parseCacheVersion = 1
+
+// parseCache stores the imports of the parsed source files across runs.
+// Entries are keyed by the path relative to the project root
+// and are valid while size, mtime or the content hash match the file.
+type parseCache struct {
+	file string
+	root string
+
+	mu      sync.Mutex
+	entries map[string]parseCacheEntry
+	// used are the entries of files seen by this run, the others are dropped on save
+	used    map[string]parseCacheEntry
+	changed bool
+}
+
+type parseCacheEntry struct {
+	Size    int64    `json:"size"`
+	ModTime int64    `json:"mtime"`
+	Hash    string   `json:"hash"`
+	Imports []Import `json:"imports"`
+}
+
+type parseCacheFile struct {
+	Version int                        `json:"version"`
+	Files   map[string]parseCacheEntry `json:"files"`
+}
+
+// loadParseCache reads the cache of the project at root.
+// A missing, outdated or broken cache is empty.
+func loadParseCache(root string) *parseCache {
+	c := &parseCache{
+		file:    filepath.Join(root, "node_modules", ".jmod", "tidy-cache.json"),
+		root:    root,
+		entries: map[string]parseCacheEntry{},
+		used:    map[string]parseCacheEntry{},
+	}
+	data, err := os.ReadFile(c.file)
+	if err != nil {
+		return c
+	}
+	var f parseCacheFile
+	if err := json.Unmarshal(data, &f); err != nil || f.Version != parseCacheVersion || f.Files == nil {
+		c.changed = true
+		return c
+	}
+	c.entries = f.Files
+	return c
+}
+
+// parse returns the imports of the file at path, parsing it only if it changed since it was cached.
+func (c *parseCache) parse(path string, parser Parser) ([]Import, error) {
+	key, err := filepath.Rel(c.root, path)
+	if err != nil {
+		return parser.ParseImports(path)
+	}
+	key = filepath.ToSlash(key)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && entry.Size == info.Size() && entry.ModTime == info.ModTime().UnixNano() {
+		c.use(key, entry, false)
+		return entry.Imports, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+	if !ok || entry.Hash != hash {
+		imports, err := parser.ParseImports(path)
+		if err != nil {
+			return nil, err
+		}
+		entry.Imports = imports
+	}
+	// a touched file keeps its imports, only its size and mtime are updated
+	entry.Size = info.Size()
+	entry.ModTime = info.ModTime().UnixNano()
+	entry.Hash = hash
+	c.use(key, entry, true)
+	return entry.Imports, nil
+}
+
+func (c *parseCache) use(key string, entry parseCacheEntry, changed bool) {
+	c.mu.Lock()
+	c.used[key] = entry
+	c.changed = c.changed || changed
+	c.mu.Unlock()
+}
+
+// save writes the entries of the files seen by this run if any of them changed or disappeared.
+func (c *parseCache) save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.changed && len(c.used) == len(c.entries) {
+		return nil
+	}
+	data, err := json.Marshal(parseCacheFile{Version: parseCacheVersion, Files: c.used})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.file), 0o755); err != nil {
+		return err
+	}
+	tmp := c.file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, c.file); err != nil {
+		return errors.Join(err, os.Remove(tmp))
+	}
+	return nil
+}
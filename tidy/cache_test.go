@@ -0,0 +1,56 @@
+package tidy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type countingParser struct{ calls int }
+
+func (p *countingParser) ParseImports(path string) ([]Import, error) {
+	p.calls++
+	return []Import{{Specifier: "react"}}, nil
+}
+
+func TestParseCache(t *testing.T) {
+	root := t.TempDir()
+	file := filepath.Join(root, "index.ts")
+	if err := os.WriteFile(file, []byte(`import "react"`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	parser := &countingParser{}
+
+	cache := loadParseCache(root)
+	if _, err := cache.parse(file, parser); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// unchanged and touched files are not parsed again
+	cache = loadParseCache(root)
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(file, later, later); err != nil {
+		t.Fatal(err)
+	}
+	imports, err := cache.parse(file, parser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parser.calls != 1 || len(imports) != 1 || imports[0].Specifier != "react" {
+		t.Fatalf("got %v after %d parses, want cached import of react", imports, parser.calls)
+	}
+
+	if err := os.WriteFile(file, []byte(`import "vue"`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.parse(file, parser); err != nil {
+		t.Fatal(err)
+	}
+	if parser.calls != 2 {
+		t.Errorf("changed file was not parsed again")
+	}
+}
@@ -0,0 +1,65 @@
+package tidy
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	"github.com/tsukinoko-kun/jmod/config"
+)
+
+// usage tells whether a package is imported by production code, development code or both.
+type usage uint8
+
+const (
+	usageDev usage = 1 << iota
+	usageProd
+)
+
+// defaultDevFiles are the files whose imports belong in devDependencies.
+// Tool directories and config files are anchored to the module root,
+// src/scripts/ or Angular's src/app/app.config.ts are application code.
+var defaultDevFiles = []string{
+	"*.test.*",
+	"*.spec.*",
+	"*.stories.*",
+	"*.story.*",
+	"*.bench.*",
+	"/*.config.*",
+	".*rc.*",
+	"__tests__/",
+	"__mocks__/",
+	"__fixtures__/",
+	"/test/",
+	"/tests/",
+	"/e2e/",
+	"cypress/",
+	".storybook/",
+	"/scripts/",
+}
+
+// fileClassifier decides whether a source file is production or development code.
+type fileClassifier struct {
+	dev gitignore.Matcher
+}
+
+func newFileClassifier(settings *config.TidySettings) fileClassifier {
+	patterns := defaultDevFiles
+	if settings != nil && len(settings.DevFiles) != 0 {
+		patterns = settings.DevFiles
+	}
+	dev := make([]gitignore.Pattern, 0, len(patterns))
+	for _, pattern := range patterns {
+		dev = append(dev, gitignore.ParsePattern(pattern, nil))
+	}
+	return fileClassifier{dev: gitignore.NewMatcher(dev)}
+}
+
+// classify returns the usage of the imports of the file at rel, a path relative to the module root.
+// The file is development code if it or one of its parent directories matches a dev pattern.
+func (c fileClassifier) classify(rel string) usage {
+	if c.dev.Match(strings.Split(filepath.ToSlash(rel), "/"), false) {
+		return usageDev
+	}
+	return usageProd
+}
@@ -0,0 +1,27 @@
+package tidy
+
+import "testing"
+
+func TestClassify(t *testing.T) {
+	c := newFileClassifier(nil)
+	for rel, want := range map[string]usage{
+		"src/index.ts":             usageProd,
+		"src/button.tsx":           usageProd,
+		"src/button.test.tsx":      usageDev,
+		"src/button.stories.tsx":   usageDev,
+		"src/__tests__/button.tsx": usageDev,
+		"vite.config.ts":           usageDev,
+		"scripts/release.ts":       usageDev,
+		"src/scripts/loader.ts":    usageProd,
+		"src/app/app.config.ts":    usageProd,
+		"src/testing/helpers.ts":   usageProd,
+		"src/test/fixtures.ts":     usageProd,
+		"test/setup.ts":            usageDev,
+		"tests/unit/button.ts":     usageDev,
+		"e2e/login.ts":             usageDev,
+	} {
+		if got := c.classify(rel); got != want {
+			t.Errorf("classify(%s) = %d, want %d", rel, got, want)
+		}
+	}
+}
@@ -2,7 +2,10 @@ package tidy
 
 import (
 	"fmt"
+	"maps"
 	"os"
+	"regexp"
+	"slices"
 	"strings"
 
 	tree_sitter "github.com/tree-sitter/go-tree-sitter"
@@ -24,40 +27,82 @@ func init() {
 }
 
 // ParseImports parses a TypeScript/TSX file and returns all imported package names
-func (p DefaultParser) ParseImports(filePath string) ([]string, error) {
+func (p DefaultParser) ParseImports(filePath string) ([]Import, error) {
 	// Read the file content
 	content, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
 
-	// Create parser
-	parser := tree_sitter.NewParser()
-	tsxLang := tree_sitter.NewLanguage(tree_sitter_tsx.LanguageTSX())
-	parser.SetLanguage(tsxLang)
+	imports := make(map[string]bool)
+	if err := parseSource(content, imports); err != nil {
+		return nil, err
+	}
+	return importList(imports), nil
+}
+
+var (
+	tsxLanguage = tree_sitter.NewLanguage(tree_sitter_tsx.LanguageTSX())
+	// idleParsers keeps tree-sitter parsers for reuse, at most one per parse worker
+	idleParsers = make(chan *tree_sitter.Parser, parseWorkers)
+)
+
+func acquireParser() *tree_sitter.Parser {
+	select {
+	case parser := <-idleParsers:
+		return parser
+	default:
+		parser := tree_sitter.NewParser()
+		parser.SetLanguage(tsxLanguage)
+		return parser
+	}
+}
+
+func releaseParser(parser *tree_sitter.Parser) {
+	parser.Reset()
+	select {
+	case idleParsers <- parser:
+	default:
+		parser.Close()
+	}
+}
+
+// parseSource adds the imports of a TypeScript/TSX source to imports.
+// The value is true while every import of the specifier is type-only.
+func parseSource(content []byte, imports map[string]bool) error {
+	parser := acquireParser()
+	defer releaseParser(parser)
 
 	// Parse the content
 	tree := parser.Parse(content, nil)
 	if tree == nil {
-		return nil, fmt.Errorf("failed to parse file")
+		return fmt.Errorf("failed to parse file")
 	}
 	defer tree.Close()
 
-	root := tree.RootNode()
-
-	// Collect all imports
-	imports := make(map[string]bool)
-
 	// Process the AST to find imports
-	processNode(root, content, imports)
+	processNode(tree.RootNode(), content, imports)
+	return nil
+}
 
-	// Convert map to slice
-	result := make([]string, 0, len(imports))
-	for pkg := range imports {
-		result = append(result, pkg)
+func importList(imports map[string]bool) []Import {
+	result := make([]Import, 0, len(imports))
+	for _, spec := range slices.Sorted(maps.Keys(imports)) {
+		result = append(result, Import{Specifier: spec, TypeOnly: imports[spec]})
 	}
+	return result
+}
 
-	return result, nil
+// addImport records an import, a runtime import of a specifier wins over type-only ones.
+func addImport(imports map[string]bool, spec string, typeOnly bool) {
+	if spec == "" || isRelativePath(spec) {
+		return
+	}
+	if seenTypeOnly, ok := imports[spec]; ok {
+		imports[spec] = seenTypeOnly && typeOnly
+		return
+	}
+	imports[spec] = typeOnly
 }
 
 // processNode recursively processes AST nodes to find imports
@@ -66,12 +111,20 @@ func processNode(node *tree_sitter.Node, source []byte, imports map[string]bool)
 
 	switch nodeType {
 	case "import_statement":
-		// Handle ES6 imports: import ... from "package"
+		// Handle ES6 imports: import ... from "package" and import x = require("package")
 		processImportStatement(node, source, imports)
 
+	case "export_statement":
+		// Handle re-exports: export ... from "package"
+		processExportStatement(node, source, imports)
+
 	case "call_expression":
-		// Handle require() and dynamic import()
+		// Handle require(), dynamic import() and import.meta.resolve()
 		processCallExpression(node, source, imports)
+
+	case "comment":
+		// Handle /// <reference types="package" />
+		processComment(node, source, imports)
 	}
 
 	// Recursively process children
@@ -83,19 +136,67 @@ func processNode(node *tree_sitter.Node, source []byte, imports map[string]bool)
 
 // processImportStatement extracts package name from import statements
 func processImportStatement(node *tree_sitter.Node, source []byte, imports map[string]bool) {
-	// Find the string node (the module specifier)
+	typeOnly := false
 	for i := uint(0); i < node.ChildCount(); i++ {
 		child := node.Child(i)
-		if child.Kind() == "string" {
-			pkgName := extractStringValue(child, source)
-			if pkgName != "" && !isRelativePath(pkgName) {
-				imports[pkgName] = true
+		switch child.Kind() {
+		case "type":
+			// import type { A } from "package"
+			typeOnly = true
+		case "import_clause":
+			typeOnly = typeOnly || onlyTypeSpecifiers(child)
+		case "string":
+			addImport(imports, extractStringValue(child, source), typeOnly)
+		case "import_require_clause":
+			// import x = require("package")
+			for j := uint(0); j < child.ChildCount(); j++ {
+				if arg := child.Child(j); arg.Kind() == "string" {
+					addImport(imports, extractStringValue(arg, source), false)
+				}
 			}
 		}
 	}
 }
 
-// processCallExpression handles require() and import() calls
+// onlyTypeSpecifiers reports whether an import clause only imports types like { type A, type B }.
+func onlyTypeSpecifiers(clause *tree_sitter.Node) bool {
+	specifiers := 0
+	for i := uint(0); i < clause.ChildCount(); i++ {
+		child := clause.Child(i)
+		if child.Kind() != "named_imports" {
+			// default or namespace import
+			return false
+		}
+		for j := uint(0); j < child.ChildCount(); j++ {
+			specifier := child.Child(j)
+			if specifier.Kind() != "import_specifier" {
+				continue
+			}
+			specifiers++
+			if specifier.ChildCount() == 0 || specifier.Child(0).Kind() != "type" {
+				return false
+			}
+		}
+	}
+	return specifiers > 0
+}
+
+// processExportStatement extracts package name from export ... from statements
+func processExportStatement(node *tree_sitter.Node, source []byte, imports map[string]bool) {
+	typeOnly := false
+	for i := uint(0); i < node.ChildCount(); i++ {
+		child := node.Child(i)
+		switch child.Kind() {
+		case "type":
+			// export type { A } from "package"
+			typeOnly = true
+		case "string":
+			addImport(imports, extractStringValue(child, source), typeOnly)
+		}
+	}
+}
+
+// processCallExpression handles require(), import() and import.meta.resolve() calls
 func processCallExpression(node *tree_sitter.Node, source []byte, imports map[string]bool) {
 	if node.ChildCount() < 2 {
 		return
@@ -110,7 +211,7 @@ func processCallExpression(node *tree_sitter.Node, source []byte, imports map[st
 	fnText := string(source[fn.StartByte():fn.EndByte()])
 
 	// Check if it's require or import
-	if fnText == "require" || fnText == "import" {
+	if fnText == "require" || fnText == "import" || fnText == "require.resolve" || fnText == "import.meta.resolve" {
 		// Get the arguments node
 		args := node.ChildByFieldName("arguments")
 		if args == nil {
@@ -121,15 +222,21 @@ func processCallExpression(node *tree_sitter.Node, source []byte, imports map[st
 		for i := uint(0); i < args.ChildCount(); i++ {
 			arg := args.Child(i)
 			if arg.Kind() == "string" {
-				pkgName := extractStringValue(arg, source)
-				if pkgName != "" && !isRelativePath(pkgName) {
-					imports[pkgName] = true
-				}
+				addImport(imports, extractStringValue(arg, source), false)
 			}
 		}
 	}
 }
 
+var referenceTypes = regexp.MustCompile(`^///\s*<reference\s+types\s*=\s*["']([^"']+)["']`)
+
+// processComment handles triple-slash type references
+func processComment(node *tree_sitter.Node, source []byte, imports map[string]bool) {
+	if m := referenceTypes.FindSubmatch(source[node.StartByte():node.EndByte()]); m != nil {
+		addImport(imports, string(m[1]), true)
+	}
+}
+
 // extractStringValue extracts the actual string value from a string node
 func extractStringValue(node *tree_sitter.Node, source []byte) string {
 	if node.Kind() != "string" {
@@ -145,11 +252,12 @@ func extractStringValue(node *tree_sitter.Node, source []byte) string {
 	return raw
 }
 
-// isRelativePath checks if a path is relative (starts with ./ or ../ or ~/)
+// isRelativePath checks if a path is relative (starts with ./ or ../) or absolute.
+// Aliases are resolved by the module, see aliases.
 func isRelativePath(path string) bool {
 	return strings.HasPrefix(path, "./") ||
 		strings.HasPrefix(path, "../") ||
-		strings.HasPrefix(path, "~/") ||
-		strings.HasPrefix(path, "@/") ||
+		path == "." ||
+		path == ".." ||
 		strings.HasPrefix(path, "/")
 }
@@ -0,0 +1,92 @@
+package tidy
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// EmbeddedParser parses the scripts embedded in component and document formats
+// with the TypeScript parser.
+type EmbeddedParser struct {
+	// extract returns the script sources of a file
+	extract func(content []byte) [][]byte
+}
+
+func init() {
+	parsers[".vue"] = EmbeddedParser{extract: scriptTags}
+	parsers[".svelte"] = EmbeddedParser{extract: scriptTags}
+	parsers[".astro"] = EmbeddedParser{extract: astroScripts}
+	parsers[".mdx"] = EmbeddedParser{extract: mdxESM}
+}
+
+func (p EmbeddedParser) ParseImports(filePath string) ([]Import, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	imports := make(map[string]bool)
+	for _, script := range p.extract(content) {
+		if err := parseSource(script, imports); err != nil {
+			return nil, fmt.Errorf("%s: %w", filePath, err)
+		}
+	}
+	return importList(imports), nil
+}
+
+var scriptTag = regexp.MustCompile(`(?is)<script\b[^>]*>(.*?)</script\s*>`)
+
+// scriptTags returns the content of the <script> elements of .vue and .svelte files.
+func scriptTags(content []byte) [][]byte {
+	var scripts [][]byte
+	for _, m := range scriptTag.FindAllSubmatch(content, -1) {
+		scripts = append(scripts, m[1])
+	}
+	return scripts
+}
+
+var astroFrontmatter = regexp.MustCompile(`(?s)\A\s*---\r?\n(.*?)\r?\n---`)
+
+// astroScripts returns the frontmatter and the <script> elements of .astro files.
+func astroScripts(content []byte) [][]byte {
+	var scripts [][]byte
+	if m := astroFrontmatter.FindSubmatch(content); m != nil {
+		scripts = append(scripts, m[1])
+		content = content[len(m[0]):]
+	}
+	return append(scripts, scriptTags(content)...)
+}
+
+// mdxESM returns the import and export blocks of .mdx files.
+// They are paragraphs starting with import or export outside of code fences.
+func mdxESM(content []byte) [][]byte {
+	var esm bytes.Buffer
+	inFence := false
+	inBlock := false
+	for line := range bytes.Lines(content) {
+		trimmed := bytes.TrimSpace(line)
+		if bytes.HasPrefix(trimmed, []byte("```")) || bytes.HasPrefix(trimmed, []byte("~~~")) {
+			inFence = !inFence
+			inBlock = false
+			continue
+		}
+		if inFence {
+			continue
+		}
+		switch {
+		case len(trimmed) == 0:
+			inBlock = false
+		case bytes.HasPrefix(line, []byte("import ")) || bytes.HasPrefix(line, []byte("export ")):
+			inBlock = true
+		}
+		if inBlock {
+			esm.Write(line)
+		}
+	}
+	if esm.Len() == 0 {
+		return nil
+	}
+	return [][]byte{esm.Bytes()}
+}
@@ -0,0 +1,76 @@
+package tidy
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestParseImports(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"index.ts": `import a from "a"
+import type { B } from "b"
+import { type C } from "c"
+import { type D, d } from "d"
+export * from "e"
+export type { F } from "f"
+import g = require("g")
+const h = require.resolve("h")
+const i = import.meta.resolve("i")
+const j = await import("j")
+/// <reference types="k" />
+`,
+		"App.vue": `<template><div /></template>
+<script setup lang="ts">
+import vue from "vue"
+</script>
+`,
+		"page.mdx": "import { Chart } from 'chart'\n\n```js\nimport nope from 'nope'\n```\n\n# Title\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for name, want := range map[string][]Import{
+		"index.ts": {
+			{Specifier: "a"},
+			{Specifier: "b", TypeOnly: true},
+			{Specifier: "c", TypeOnly: true},
+			{Specifier: "d"},
+			{Specifier: "e"},
+			{Specifier: "f", TypeOnly: true},
+			{Specifier: "g"},
+			{Specifier: "h"},
+			{Specifier: "i"},
+			{Specifier: "j"},
+			{Specifier: "k", TypeOnly: true},
+		},
+		"App.vue":  {{Specifier: "vue"}},
+		"page.mdx": {{Specifier: "chart"}},
+	} {
+		path := filepath.Join(dir, name)
+		got, err := parsers[filepath.Ext(path)].ParseImports(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: got %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestTypesPackageName(t *testing.T) {
+	for name, want := range map[string]string{
+		"react":       "@types/react",
+		"@babel/core": "@types/babel__core",
+		"node":        "@types/node",
+	} {
+		if got := typesPackageName(name); got != want {
+			t.Errorf("typesPackageName(%s) = %s, want %s", name, got, want)
+		}
+	}
+}
@@ -1,151 +1,333 @@
 package tidy
 
 import (
+	"errors"
 	"fmt"
 	"io/fs"
+	"maps"
 	"path/filepath"
+	"regexp"
 	"slices"
 	"strings"
 	"sync"
 
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
 	"github.com/tsukinoko-kun/jmod/config"
 	"github.com/tsukinoko-kun/jmod/ignore"
 	"github.com/tsukinoko-kun/jmod/logger"
 	"github.com/tsukinoko-kun/jmod/meta"
-	"github.com/tsukinoko-kun/jmod/registry"
 	"github.com/tsukinoko-kun/jmod/utils"
+	json "github.com/tsukinoko-kun/jsonedit"
 )
 
+// Import is a module specifier found in a source file.
+type Import struct {
+	Specifier string
+	// TypeOnly is set if the file only uses types of the module,
+	// like import type or /// <reference types="..." />
+	TypeOnly bool
+}
+
 type Parser interface {
-	ParseImports(path string) ([]string, error)
+	ParseImports(path string) ([]Import, error)
 }
 
 var parsers = map[string]Parser{}
 
-func Run(root string) error {
+// Options configures Run.
+type Options struct {
+	// Prune removes dependencies that are not used anywhere, otherwise they are only reported
+	Prune bool
+	// DryRun reports the changes without writing any package.json
+	DryRun bool
+	// Check is DryRun that fails with ErrNotTidy if a package.json would change
+	Check bool
+}
+
+// ErrNotTidy is returned by Run in check mode if a package.json would change.
+var ErrNotTidy = errors.New("package.json is not tidy, run jmod tidy")
+
+// module is a workspace module with the packages imported by its source files.
+type module struct {
+	doc     *json.Document[*config.Mod]
+	root    string
+	imports map[string]usage
+	// typescript is set if the module has TypeScript sources
+	typescript bool
+}
+
+func Run(root string, opts Options) error {
 	ignoreMatcher := ignore.GetIgnoreMatcher(root)
+	settings := config.LoadSettings(root)
+	classifier := newFileClassifier(settings.Tidy)
 
 	mods := config.FindSubMods(root)
 
 	var ignoreModDirs []string
+	workspaces := make(map[string]bool, len(mods))
 	for _, modDoc := range mods {
 		mod := modDoc.TypedData
 		ignoreModDirs = append(ignoreModDirs, filepath.Dir(mod.GetFileLocation()))
+		if pj, err := config.GetPackageJsonForLifecycle(mod.GetFileLocation()); err == nil && pj.Name != nil {
+			workspaces[*pj.Name] = true
+		}
 	}
 
+	modules := make([]*module, 0, len(mods))
 	for _, modDoc := range mods {
-		mod := modDoc.TypedData
-		root := filepath.Dir(mod.GetFileLocation())
-		modImports := make(map[string]bool)
-		var mu sync.Mutex
-		var wg sync.WaitGroup
-		errChan := make(chan error, 1)
-
-		if err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
-			if err != nil {
-				return err
-			}
+		modules = append(modules, &module{
+			doc:     modDoc,
+			root:    filepath.Dir(modDoc.TypedData.GetFileLocation()),
+			imports: make(map[string]usage),
+		})
+	}
+	builtins := detectBuiltins(root, modules)
+	cache := loadParseCache(root)
+	for _, m := range modules {
+		if err := m.collectImports(root, ignoreMatcher, ignoreModDirs, classifier, builtins, cache); err != nil {
+			return err
+		}
+	}
+	if err := cache.save(); err != nil {
+		logger.Printf("failed to save tidy cache: %s", err)
+	}
 
-			isDir := d.IsDir()
-			gitPath := strings.Split(
-				strings.TrimPrefix(path, root),
-				string(filepath.Separator),
-			)
-			if len(gitPath) != 0 && gitPath[0] == "" {
-				gitPath = gitPath[1:]
-			}
-			if ignoreMatcher.Match(gitPath, isDir) {
-				if isDir {
-					return filepath.SkipDir
-				}
-				return nil
-			}
+	dryRunChanges := false
+	for _, m := range modules {
+		changed, err := m.tidy(root, modules, workspaces, settings.Tidy, opts)
+		if err != nil {
+			return err
+		}
+		if !changed {
+			continue
+		}
+		if opts.DryRun || opts.Check {
+			dryRunChanges = true
+			continue
+		}
+		if err := config.Write(m.doc); err != nil {
+			return fmt.Errorf("failed to save mod file: %w", err)
+		}
+	}
 
-			if isDir {
-				if path != root && slices.Contains(ignoreModDirs, path) {
-					return filepath.SkipDir
-				}
-				return nil
-			}
+	if dryRunChanges {
+		if opts.Check {
+			return ErrNotTidy
+		}
+		logger.Infof("dry run, no package.json was changed")
+	}
+	return nil
+}
 
-			ext := filepath.Ext(path)
-			if parser, ok := parsers[ext]; ok {
-				wg.Add(1)
-				go func(path string, parser Parser) {
-					defer wg.Done()
-					imports, err := parser.ParseImports(path)
-					if err != nil {
-						select {
-						case errChan <- err:
-						default:
-						}
-						return
-					}
-					for _, imp := range imports {
-						if !strings.HasPrefix(imp, "node:") {
-							transformed := transformForNpm(imp)
-							mu.Lock()
-							modImports[transformed] = true
-							mu.Unlock()
-						}
-					}
-				}(path, parser)
-			} else {
-				logger.Printf("no parser for %s", ext)
-			}
+// tidy adds the missing dependencies of m to the section matching their usage,
+// moves dependencies whose usage changed and reports or removes the unused ones.
+// Workspace modules are never added from the registry.
+func (m *module) tidy(root string, modules []*module, workspaces map[string]bool, settings *config.TidySettings, opts Options) (changed bool, err error) {
+	mod := m.doc.TypedData
 
-			return nil
-		}); err != nil {
-			return fmt.Errorf("failed to walk directory: %w", err)
+	if len(m.imports) != 0 {
+		logger.Printf("found %d imports for %s", len(m.imports), utils.Must(filepath.Rel(meta.Pwd(), mod.GetFileLocation())))
+	}
+
+	for _, imp := range slices.Sorted(maps.Keys(m.imports)) {
+		if isIgnored(settings, imp) {
+			continue
 		}
 
-		wg.Wait()
-		close(errChan)
+		// check if the package is already a dependency
+		if _, included := mod.NpmDevDependencies[imp]; included {
+			continue
+		}
+		if _, included := mod.NpmDependencies[imp]; included {
+			continue
+		}
+		if workspaces[imp] {
+			logger.Printf("%s imports the workspace module %s, add it as local dependency to install it", m.name(root), imp)
+			continue
+		}
+		version, err := m.versionFor(root, modules, imp)
+		if err != nil {
+			logger.Printf("failed to get version for %s: %s", imp, err)
+			continue
+		}
+		if m.imports[imp]&usageProd != 0 {
+			mod.NpmDependencies = setDependency(mod.NpmDependencies, imp, version)
+			logger.Infof("%s: + %s %s", m.name(root), imp, version)
+		} else {
+			mod.NpmDevDependencies = setDependency(mod.NpmDevDependencies, imp, version)
+			logger.Infof("%s: + %s %s (dev)", m.name(root), imp, version)
+		}
+		changed = true
+	}
 
-		if err := <-errChan; err != nil {
-			return fmt.Errorf("failed to parse imports: %w", err)
+	for _, name := range slices.Sorted(maps.Keys(mod.NpmDependencies)) {
+		if _, dev := mod.NpmDevDependencies[name]; dev {
+			continue
+		}
+		if m.usage(modules, name) == usageDev {
+			mod.NpmDevDependencies = setDependency(mod.NpmDevDependencies, name, mod.NpmDependencies[name])
+			delete(mod.NpmDependencies, name)
+			changed = true
+			logger.Infof("%s: ~ %s moved to devDependencies", m.name(root), name)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(mod.NpmDevDependencies)) {
+		if _, prod := mod.NpmDependencies[name]; prod {
+			continue
+		}
+		if m.usage(modules, name)&usageProd != 0 {
+			mod.NpmDependencies = setDependency(mod.NpmDependencies, name, mod.NpmDevDependencies[name])
+			delete(mod.NpmDevDependencies, name)
+			changed = true
+			logger.Infof("%s: ~ %s moved to dependencies", m.name(root), name)
 		}
+	}
 
-		if len(modImports) == 0 {
+	for _, name := range m.unused(modules, settings) {
+		if !opts.Prune {
+			logger.Warnf("%s: %s is not used, remove it with jmod tidy --prune", m.name(root), name)
 			continue
 		}
+		if err := config.Uninstall(m.doc, name); err != nil {
+			return changed, fmt.Errorf("failed to remove %s: %w", name, err)
+		}
+		changed = true
+		logger.Infof("%s: - %s (unused)", m.name(root), name)
+	}
+
+	return changed, nil
+}
 
-		logger.Printf("found %d imports for %s", len(modImports), utils.Must(filepath.Rel(meta.Pwd(), mod.GetFileLocation())))
+func setDependency(deps map[string]string, name, version string) map[string]string {
+	if deps == nil {
+		deps = make(map[string]string)
+	}
+	deps[name] = version
+	return deps
+}
 
-		if mod.NpmDependencies == nil {
-			mod.NpmDependencies = make(map[string]string)
-		}
+// name returns the path of the module relative to the project root for reports.
+func (m *module) name(root string) string {
+	rel, err := filepath.Rel(root, m.root)
+	if err != nil {
+		return m.root
+	}
+	return filepath.ToSlash(rel)
+}
 
-		for imp := range modImports {
-			if slices.Contains(ignoredPackages, imp) {
-				continue
-			}
+// collectImports parses the source files of the module, skipping nested modules,
+// and records for every imported package whether production or development code uses it.
+// The files are parsed by a fixed number of workers, unchanged files are taken from cache.
+// ignoreMatcher matches paths relative to projectRoot, like the one of the ignore package.
+func (m *module) collectImports(projectRoot string, ignoreMatcher gitignore.Matcher, ignoreModDirs []string, classifier fileClassifier, builtins builtins, cache *parseCache) error {
+	root := m.root
+	aliases := loadAliases(root)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	errChan := make(chan error, 1)
 
-			// check if the package is already a dependency
-			if _, included := mod.NpmDevDependencies[imp]; included {
-				continue
-			}
-			if _, included := mod.NpmDependencies[imp]; !included {
-				latestVersion, err := registry.Npm_GetLatestVersion(imp)
+	type parseJob struct {
+		path   string
+		parser Parser
+		use    usage
+	}
+	jobs := make(chan parseJob)
+	for range parseWorkers {
+		wg.Go(func() {
+			for job := range jobs {
+				imports, err := cache.parse(job.path, job.parser)
 				if err != nil {
-					logger.Printf("failed to get latest version for %s: %s", imp, err)
+					select {
+					case errChan <- err:
+					default:
+					}
 					continue
 				}
-				mod.NpmDependencies[imp] = latestVersion
-				logger.Printf("  new package %s (version %s)", imp, latestVersion)
+				for _, imp := range imports {
+					spec := imp.Specifier
+					// #internal imports are mapped by the imports field of the package.json
+					if m.importsBuiltin(builtins, spec) || strings.HasPrefix(spec, "#") || aliases.isLocal(spec) {
+						continue
+					}
+					transformed := transformForNpm(spec)
+					if !validPackageName(transformed) {
+						continue
+					}
+					if imp.TypeOnly {
+						transformed = m.typesPackage(transformed)
+					}
+					mu.Lock()
+					m.imports[transformed] |= job.use
+					mu.Unlock()
+				}
 			}
+		})
+	}
+
+	walkErr := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
 		}
 
-		if err := config.Write(modDoc); err != nil {
-			return fmt.Errorf("failed to save mod file: %w", err)
+		isDir := d.IsDir()
+		rel, err := filepath.Rel(projectRoot, path)
+		if err != nil {
+			return err
+		}
+		if rel != "." && ignoreMatcher.Match(strings.Split(filepath.ToSlash(rel), "/"), isDir) {
+			if isDir {
+				return filepath.SkipDir
+			}
+			return nil
 		}
+
+		if isDir {
+			if path != root && slices.Contains(ignoreModDirs, path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		ext := filepath.Ext(path)
+		if parser, ok := parsers[ext]; ok {
+			if isTypeScript(path) {
+				m.typescript = true
+			}
+			jobs <- parseJob{path: path, parser: parser, use: classifier.classify(utils.Must(filepath.Rel(root, path)))}
+		} else {
+			logger.Printf("no parser for %s", ext)
+		}
+
+		return nil
+	})
+	close(jobs)
+	wg.Wait()
+	close(errChan)
+
+	if walkErr != nil {
+		return fmt.Errorf("failed to walk directory: %w", walkErr)
 	}
 
+	if err := <-errChan; err != nil {
+		return fmt.Errorf("failed to parse imports: %w", err)
+	}
 	return nil
 }
 
-var ignoredPackages = []string{
-	"server-only",
+func isTypeScript(path string) bool {
+	switch filepath.Ext(path) {
+	case ".ts", ".mts", ".cts", ".tsx":
+		return true
+	default:
+		return false
+	}
+}
+
+var packageNamePattern = regexp.MustCompile(`^(@[a-z0-9-][a-z0-9-._]*/)?[a-z0-9-][a-z0-9-._]*$`)
+
+// validPackageName reports whether name can be an npm package, which rules out aliases like ~/ or @/.
+func validPackageName(name string) bool {
+	return len(name) <= 214 && packageNamePattern.MatchString(strings.ToLower(name))
 }
 
 func transformForNpm(importString string) string {
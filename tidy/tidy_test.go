@@ -0,0 +1,68 @@
+package tidy
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/tsukinoko-kun/jmod/config"
+	"github.com/tsukinoko-kun/jmod/registry"
+)
+
+func TestRunNestedModule(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/"), "/latest")
+		fmt.Fprintf(w, `{"name":%q,"version":"1.0.0"}`, name)
+	}))
+	defer srv.Close()
+	defer func(registryURL string) { registry.NpmRegistry = registryURL }(registry.NpmRegistry)
+	registry.NpmRegistry = srv.URL
+
+	root := t.TempDir()
+	for rel, content := range map[string]string{
+		"package.json":                      `{"name":"root","workspaces":["packages/*"]}`,
+		".gitignore":                        "/dist\n",
+		"src/index.ts":                      `import "root-dep"`,
+		"dist/index.js":                     `import "root-dist"`,
+		"packages/app/package.json":         `{"name":"app"}`,
+		"packages/app/.jmodignore":          "generated/\n",
+		"packages/app/src/index.ts":         `import "react"`,
+		"packages/app/dist/index.js":        `import "app-dist"`,
+		"packages/app/generated/schema.ts":  `import "generated-dep"`,
+		"packages/app/src/generated/api.ts": `import "nested-generated-dep"`,
+	} {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := Run(root, Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for dir, want := range map[string][]string{
+		// /dist only ignores the dist directory of the project root
+		root: {"root-dep"},
+		// generated/ of the nested .jmodignore applies to the nested module
+		filepath.Join(root, "packages", "app"): {"app-dist", "react"},
+	} {
+		mod, err := config.Load(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := slices.Sorted(maps.Keys(mod.TypedData.NpmDependencies))
+		if !slices.Equal(got, want) {
+			t.Errorf("dependencies of %s = %v, want %v", dir, got, want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package tidy
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// typesPackage returns the package providing the types of name:
+// name itself if it bundles its types, otherwise its @types package.
+func (m *module) typesPackage(name string) string {
+	if strings.HasPrefix(name, "@types/") {
+		return name
+	}
+	typesName := typesPackageName(name)
+	mod := m.doc.TypedData
+	if _, ok := mod.NpmDevDependencies[typesName]; ok {
+		return typesName
+	}
+	if _, ok := mod.NpmDependencies[typesName]; ok {
+		return typesName
+	}
+	bundled, installed := bundlesTypes(m.root, name)
+	if bundled {
+		return name
+	}
+	// node has no package, its types are always @types/node
+	if !installed && name != "node" {
+		// nothing to check without the package, assume it brings its types
+		return name
+	}
+	return typesName
+}
+
+// typesPackageName returns the name of the DefinitelyTyped package of name, @scope/pkg becomes @types/scope__pkg.
+func typesPackageName(name string) string {
+	if scope, pkg, ok := strings.Cut(strings.TrimPrefix(name, "@"), "/"); ok && strings.HasPrefix(name, "@") {
+		return "@types/" + scope + "__" + pkg
+	}
+	return "@types/" + name
+}
+
+// bundlesTypes reports whether the package name installed for the module at root declares types.
+func bundlesTypes(root string, name string) (bundled bool, installed bool) {
+	dir := filepath.Join(root, "node_modules", filepath.FromSlash(name))
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return false, false
+	}
+	var pj struct {
+		Types   string          `json:"types"`
+		Typings string          `json:"typings"`
+		Exports json.RawMessage `json:"exports"`
+	}
+	if err := json.Unmarshal(data, &pj); err != nil {
+		return false, true
+	}
+	if pj.Types != "" || pj.Typings != "" || strings.Contains(string(pj.Exports), `"types"`) {
+		return true, true
+	}
+	if _, err := os.Stat(filepath.Join(dir, "index.d.ts")); err == nil {
+		return true, true
+	}
+	return false, true
+}
@@ -0,0 +1,248 @@
+package tidy
+
+import (
+	_json "encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/tsukinoko-kun/jmod/config"
+)
+
+// unused returns the dependencies and devDependencies of m that nothing uses, sorted by name.
+// A dependency is used if it is imported by the module or by a nested module not declaring it itself,
+// if one of its bins or its name appears in a script, if a config file references it,
+// if it provides the types of a used package or if it is on the keep list.
+func (m *module) unused(modules []*module, settings *config.TidySettings) []string {
+	mod := m.doc.TypedData
+	var keep []string
+	if settings != nil {
+		keep = settings.Keep
+	}
+
+	declared := make([]string, 0, len(mod.NpmDependencies)+len(mod.NpmDevDependencies))
+	for name := range mod.NpmDependencies {
+		declared = append(declared, name)
+	}
+	for name := range mod.NpmDevDependencies {
+		if _, ok := mod.NpmDependencies[name]; !ok {
+			declared = append(declared, name)
+		}
+	}
+	slices.Sort(declared)
+
+	configText := m.configText()
+	used := make(map[string]bool, len(declared))
+	for _, name := range declared {
+		used[name] = m.usage(modules, name) != 0 ||
+			m.usedInScripts(name) ||
+			referenced(configText, name) ||
+			isKept(keep, name)
+	}
+
+	var unused []string
+	for _, name := range declared {
+		if used[name] {
+			continue
+		}
+		// the TypeScript compiler is used by editors and frameworks without being mentioned
+		if name == "typescript" && m.typescript {
+			continue
+		}
+		if typed, ok := typesFor(name); ok {
+			// @types/node is loaded implicitly by the TypeScript compiler
+			if used[typed] || m.imports[typed] != 0 || (typed == "node" && m.typescript) {
+				continue
+			}
+		}
+		unused = append(unused, name)
+	}
+	return unused
+}
+
+// usage returns how m and the modules nested in m that do not declare name themselves import name,
+// node resolves those imports through the node_modules of m.
+func (m *module) usage(modules []*module, name string) usage {
+	use := m.imports[name]
+	for _, other := range modules {
+		if other == m || !strings.HasPrefix(other.root, m.root+string(filepath.Separator)) {
+			continue
+		}
+		otherMod := other.doc.TypedData
+		if _, ok := otherMod.NpmDependencies[name]; ok {
+			continue
+		}
+		if _, ok := otherMod.NpmDevDependencies[name]; ok {
+			continue
+		}
+		use |= other.imports[name]
+	}
+	return use
+}
+
+// usedInScripts reports whether a script runs one of the bins of name or mentions the package,
+// like `node -r dotenv/config`. Without an installed package the name is used as bin name.
+func (m *module) usedInScripts(name string) bool {
+	scripts := m.doc.TypedData.Scripts
+	if len(scripts) == 0 {
+		return false
+	}
+	commands := []string{name}
+	if bins, err := config.BinNames(filepath.Join(m.root, "node_modules", name)); err == nil {
+		commands = append(commands, bins...)
+	} else {
+		// not installed, guess the bins
+		if _, pkg, ok := strings.Cut(name, "/"); ok {
+			commands = append(commands, pkg)
+		}
+		commands = append(commands, knownBins[name]...)
+	}
+	for _, script := range scripts {
+		for _, command := range commands {
+			if mentions(script, command) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// knownBins are the bins of common packages that are not named like the package.
+var knownBins = map[string][]string{
+	"typescript":       {"tsc", "tsserver"},
+	"@angular/cli":     {"ng"},
+	"@nestjs/cli":      {"nest"},
+	"@changesets/cli":  {"changeset"},
+	"@playwright/test": {"playwright"},
+	"@vue/cli":         {"vue"},
+	"@biomejs/biome":   {"biome"},
+	"npm-run-all":      {"run-p", "run-s"},
+	"npm-run-all2":     {"run-p", "run-s"},
+}
+
+// configText returns the content of the config files of the module.
+// The package.json is included without its dependency lists so declaring a package does not count as using it.
+func (m *module) configText() string {
+	var b strings.Builder
+	if data, err := os.ReadFile(m.doc.TypedData.GetFileLocation()); err == nil {
+		var pj map[string]_json.RawMessage
+		if _json.Unmarshal(data, &pj) == nil {
+			for _, field := range []string{"name", "version", "dependencies", "devDependencies", "optionalDependencies", "peerDependencies", "jmod"} {
+				delete(pj, field)
+			}
+			if data, err := _json.Marshal(pj); err == nil {
+				b.Write(data)
+				b.WriteByte('\n')
+			}
+		}
+	}
+	entries, err := os.ReadDir(m.root)
+	if err != nil {
+		return b.String()
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !isConfigFile(entry.Name()) {
+			continue
+		}
+		if data, err := os.ReadFile(filepath.Join(m.root, entry.Name())); err == nil {
+			b.Write(data)
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+// isConfigFile matches tool configs like .eslintrc.json, .prettierrc, tsconfig.json or vite.config.ts.
+func isConfigFile(name string) bool {
+	if name == "package.json" {
+		return false
+	}
+	if strings.HasPrefix(name, ".") && strings.Contains(name, "rc") {
+		return true
+	}
+	if strings.HasPrefix(name, "tsconfig") || strings.HasPrefix(name, "jsconfig") {
+		return true
+	}
+	return strings.Contains(name, ".config.") || strings.HasSuffix(name, ".config")
+}
+
+// referenced reports whether a config references the package by its name
+// or by the short name plugin and preset conventions allow, like "prettier" for eslint-config-prettier.
+func referenced(configText string, name string) bool {
+	if configText == "" {
+		return false
+	}
+	if mentions(configText, name) {
+		return true
+	}
+	for _, short := range shortNames(name) {
+		if quoted(configText, short) {
+			return true
+		}
+	}
+	return false
+}
+
+var shortNamePrefixes = []string{"eslint-plugin-", "eslint-config-", "babel-plugin-", "babel-preset-", "stylelint-config-"}
+
+func shortNames(name string) []string {
+	scope, pkg := "", name
+	if strings.HasPrefix(name, "@") {
+		if i := strings.Index(name, "/"); i > 0 {
+			scope, pkg = name[:i], name[i+1:]
+		}
+	}
+	if scope == "@types" {
+		return []string{pkg}
+	}
+	var names []string
+	for _, prefix := range shortNamePrefixes {
+		if short, ok := strings.CutPrefix(pkg, prefix); ok {
+			names = append(names, path.Join(scope, short))
+		} else if scope != "" && pkg == strings.TrimSuffix(prefix, "-") {
+			// @scope/eslint-plugin is referenced as @scope
+			names = append(names, scope)
+		}
+	}
+	return names
+}
+
+// mentions reports whether name appears in text as a whole word,
+// followed by a path like dotenv/config or preceded by a prefix like plugin:.
+func mentions(text string, name string) bool {
+	re, err := regexp.Compile(`(^|[^\w@./-])` + regexp.QuoteMeta(name) + `($|[^\w-])`)
+	return err == nil && re.MatchString(text)
+}
+
+// quoted reports whether name appears in text as a string, optionally with a plugin: prefix or a path.
+func quoted(text string, name string) bool {
+	re, err := regexp.Compile(`["'` + "`" + `](plugin:)?` + regexp.QuoteMeta(name) + `["'/` + "`" + `]`)
+	return err == nil && re.MatchString(text)
+}
+
+// typesFor returns the package @types/... provides the types of, @types/scope__pkg is for @scope/pkg.
+func typesFor(name string) (string, bool) {
+	pkg, ok := strings.CutPrefix(name, "@types/")
+	if !ok {
+		return "", false
+	}
+	if scope, rest, ok := strings.Cut(pkg, "__"); ok {
+		return "@" + scope + "/" + rest, true
+	}
+	return pkg, true
+}
+
+func isKept(keep []string, name string) bool {
+	for _, pattern := range keep {
+		if pattern == name {
+			return true
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package tidy
+
+import "testing"
+
+func TestReferences(t *testing.T) {
+	for _, tc := range []struct {
+		text, name string
+		want       bool
+	}{
+		{"node -r dotenv/config server.js", "dotenv", true},
+		{"tsc -p . && vite build", "tsc", true},
+		{"tsc-watch", "tsc", false},
+		{"eslint --fix .", "eslint", true},
+		{`{"extends": ["plugin:react/recommended", "prettier"]}`, "eslint-plugin-react", true},
+		{`{"extends": ["prettier"]}`, "eslint-config-prettier", true},
+		{`{"plugins": ["@typescript-eslint"]}`, "@typescript-eslint/eslint-plugin", true},
+		{`{"compilerOptions": {"types": ["vite/client"]}}`, "vite", true},
+		{`{"plugins": ["prettier-plugin-tailwindcss"]}`, "prettier-plugin-tailwindcss", true},
+		{`{"extends": ["next"]}`, "eslint-plugin-react", false},
+	} {
+		if got := mentions(tc.text, tc.name) || referenced(tc.text, tc.name); got != tc.want {
+			t.Errorf("%q references %s = %v, want %v", tc.text, tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestTypesFor(t *testing.T) {
+	for name, want := range map[string]string{
+		"@types/react":       "react",
+		"@types/babel__core": "@babel/core",
+		"react":              "",
+	} {
+		got, _ := typesFor(name)
+		if got != want {
+			t.Errorf("typesFor(%s) = %q, want %q", name, got, want)
+		}
+	}
+}
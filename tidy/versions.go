@@ -0,0 +1,50 @@
+package tidy
+
+import (
+	"cmp"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/tsukinoko-kun/jmod/config"
+	"github.com/tsukinoko-kun/jmod/registry"
+)
+
+// versionFor returns the version to add name with, keeping the workspace consistent:
+// the range other modules already depend on, then a caret range of the installed version, then the latest version.
+func (m *module) versionFor(root string, modules []*module, name string) (string, error) {
+	if version, ok := workspaceRange(modules, name); ok {
+		return version, nil
+	}
+	for _, dir := range []string{m.root, root} {
+		pj, err := config.GetPackageJsonForLifecycle(filepath.Join(dir, "node_modules", filepath.FromSlash(name), "package.json"))
+		if err == nil && pj.Version != nil && *pj.Version != "" {
+			return "^" + *pj.Version, nil
+		}
+	}
+	return registry.Npm_GetLatestVersion(name)
+}
+
+// workspaceRange returns the range most modules declare for name.
+// Paths are relative to the declaring module, so file: and link: ranges are not reused.
+func workspaceRange(modules []*module, name string) (string, bool) {
+	counts := map[string]int{}
+	for _, other := range modules {
+		mod := other.doc.TypedData
+		for _, deps := range []map[string]string{mod.NpmDependencies, mod.NpmDevDependencies, mod.NpmOptionalDependencies} {
+			version, ok := deps[name]
+			if !ok || strings.HasPrefix(version, "file:") || strings.HasPrefix(version, "link:") {
+				continue
+			}
+			counts[version]++
+		}
+	}
+	if len(counts) == 0 {
+		return "", false
+	}
+	// sorted first, so ties are resolved the same way on every run
+	return slices.MaxFunc(slices.Sorted(maps.Keys(counts)), func(a, b string) int {
+		return cmp.Or(cmp.Compare(counts[a], counts[b]), cmp.Compare(b, a))
+	}), true
+}
@@ -0,0 +1,125 @@
+package tidy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tsukinoko-kun/jmod/config"
+	json "github.com/tsukinoko-kun/jsonedit"
+)
+
+func testModule(root string, deps, devDeps map[string]string) *module {
+	return &module{
+		root: root,
+		doc: &json.Document[*config.Mod]{TypedData: &config.Mod{
+			NpmDependencies:    deps,
+			NpmDevDependencies: devDeps,
+		}},
+	}
+}
+
+func TestWorkspaceRange(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		pkg     string
+		modules []*module
+		want    string
+		ok      bool
+	}{
+		{
+			name:    "missing",
+			pkg:     "vue",
+			modules: []*module{testModule("", map[string]string{"react": "^18.0.0"}, nil)},
+		},
+		{
+			name: "most common",
+			pkg:  "zod",
+			modules: []*module{
+				testModule("", map[string]string{"zod": "^3.0.0"}, nil),
+				testModule("", nil, map[string]string{"zod": "^3.22.0"}),
+				testModule("", map[string]string{"zod": "^3.22.0"}, nil),
+			},
+			want: "^3.22.0",
+			ok:   true,
+		},
+		{
+			name: "tie",
+			pkg:  "zod",
+			modules: []*module{
+				testModule("", map[string]string{"zod": "^3.22.0"}, nil),
+				testModule("", map[string]string{"zod": "^3.0.0"}, nil),
+			},
+			want: "^3.0.0",
+			ok:   true,
+		},
+		{
+			name: "local paths",
+			pkg:  "shared",
+			modules: []*module{
+				testModule("", map[string]string{"shared": "file:../shared"}, nil),
+				testModule("", map[string]string{"shared": "link:../shared"}, nil),
+			},
+		},
+		{
+			name: "local paths are not counted",
+			pkg:  "shared",
+			modules: []*module{
+				testModule("", map[string]string{"shared": "file:../shared"}, nil),
+				testModule("", map[string]string{"shared": "file:../shared"}, nil),
+				testModule("", map[string]string{"shared": "^1.0.0"}, nil),
+			},
+			want: "^1.0.0",
+			ok:   true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := workspaceRange(tc.modules, tc.pkg)
+			if got != tc.want || ok != tc.ok {
+				t.Errorf("workspaceRange() = %q, %v, want %q, %v", got, ok, tc.want, tc.ok)
+			}
+		})
+	}
+}
+
+func TestVersionForInstalled(t *testing.T) {
+	root := t.TempDir()
+	app := filepath.Join(root, "packages", "app")
+	for dir, version := range map[string]string{
+		filepath.Join(root, "node_modules", "hoisted"):       "1.2.3",
+		filepath.Join(root, "node_modules", "@scope", "pkg"): "4.5.6",
+		filepath.Join(app, "node_modules", "@scope", "pkg"):  "4.0.0",
+	} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"version":"`+version+`"}`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := testModule(app, nil, nil)
+	other := testModule(root, map[string]string{"hoisted": "~1.2.0"}, nil)
+	for name, want := range map[string]string{
+		// the range of the workspace wins over the installed version
+		"hoisted": "~1.2.0",
+		// the module's own node_modules wins over the root
+		"@scope/pkg": "^4.0.0",
+	} {
+		got, err := m.versionFor(root, []*module{m, other}, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("versionFor(%s) = %q, want %q", name, got, want)
+		}
+	}
+
+	got, err := m.versionFor(root, []*module{m}, "hoisted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "^1.2.3" {
+		t.Errorf("versionFor(hoisted) without a workspace range = %q, want ^1.2.3", got)
+	}
+}